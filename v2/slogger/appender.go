@@ -25,6 +25,19 @@ type Appender interface {
 	Flush() error
 }
 
+// A Reopener is an Appender (or anything else) that can reopen its
+// output, for example after the file it writes to was moved by an
+// external log rotation tool.
+type Reopener interface {
+	Reopen() error
+}
+
+// A Rotator is an Appender (or anything else) that can rotate its
+// output on demand.
+type Rotator interface {
+	Rotate() error
+}
+
 var formatLogFunc = FormatLog
 
 func GetFormatLogFunc() func(log *Log) string {
@@ -159,6 +172,10 @@ type Filter func(log *Log) bool
 type FilterAppender struct {
 	Appender Appender
 	Filter   Filter
+
+	// threshold is set for FilterAppenders created by LevelFilter
+	// and AtomicLevelFilter
+	threshold *AtomicLevel
 }
 
 func (self *FilterAppender) Append(log *Log) error {
@@ -173,13 +190,47 @@ func (self *FilterAppender) Flush() error {
 	return self.Appender.Flush()
 }
 
+// WrappedAppender returns the Appender that logs are passed to.
+func (self *FilterAppender) WrappedAppender() Appender {
+	return self.Appender
+}
+
+// LevelThreshold returns the level of a FilterAppender created by
+// LevelFilter or AtomicLevelFilter.  ok is false for other
+// FilterAppenders.
+func (self *FilterAppender) LevelThreshold() (level Level, ok bool) {
+	if self.threshold == nil {
+		return OFF, false
+	}
+	return self.threshold.Level(), true
+}
+
+// SetLevelThreshold changes the level of a FilterAppender created by
+// LevelFilter or AtomicLevelFilter, and so of all filters sharing its
+// AtomicLevel.  It returns false, changing nothing, for other
+// FilterAppenders.
+func (self *FilterAppender) SetLevelThreshold(level Level) bool {
+	if self.threshold == nil {
+		return false
+	}
+	self.threshold.SetLevel(level)
+	return true
+}
+
 func LevelFilter(threshold Level, appender Appender) *FilterAppender {
+	return AtomicLevelFilter(NewAtomicLevel(threshold), appender)
+}
+
+// AtomicLevelFilter is like LevelFilter, but the level can be changed
+// at any time through threshold.
+func AtomicLevelFilter(threshold *AtomicLevel, appender Appender) *FilterAppender {
 	filterFunc := func(log *Log) bool {
-		return log.Level >= threshold
+		return threshold.Enabled(log.Level)
 	}
 
 	return &FilterAppender{
-		Appender: appender,
-		Filter:   filterFunc,
+		Appender:  appender,
+		Filter:    filterFunc,
+		threshold: threshold,
 	}
 }
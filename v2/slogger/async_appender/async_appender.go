@@ -71,6 +71,11 @@ func (self *AsyncAppender) Flush() error {
 	return nil
 }
 
+// WrappedAppender returns the Appender that logs are passed to.
+func (self *AsyncAppender) WrappedAppender() slogger.Appender {
+	return self.Appender
+}
+
 func (self *AsyncAppender) appendToSubAppender(log *slogger.Log) {
 	if err := self.Appender.Append(log); err != nil && self.errHandler != nil {
 		self.errHandler(err)
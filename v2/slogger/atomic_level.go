@@ -0,0 +1,63 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import "sync/atomic"
+
+// AtomicLevel is a Level that can be read and changed concurrently
+// without locking.  Filters created with AtomicLevelFilter and
+// TurboAtomicLevelFilter follow changes to their AtomicLevel at once,
+// so sharing one AtomicLevel among several loggers changes the
+// verbosity of all of them.  The zero value is TRACE.
+type AtomicLevel struct {
+	level uint32
+}
+
+func NewAtomicLevel(level Level) *AtomicLevel {
+	return &AtomicLevel{uint32(level)}
+}
+
+func (self *AtomicLevel) Level() Level {
+	return Level(atomic.LoadUint32(&self.level))
+}
+
+func (self *AtomicLevel) SetLevel(level Level) {
+	atomic.StoreUint32(&self.level, uint32(level))
+}
+
+// Enabled returns true if logs of the given level pass the filters
+// using this AtomicLevel.
+func (self *AtomicLevel) Enabled(level Level) bool {
+	return level >= self.Level()
+}
+
+func (self *AtomicLevel) String() string {
+	return self.Level().String()
+}
+
+func (self *AtomicLevel) MarshalText() ([]byte, error) {
+	return []byte(self.Level().Type()), nil
+}
+
+// UnmarshalText sets the level from its name, as parsed by NewLevel.
+// The level is not changed if the name is unknown.
+func (self *AtomicLevel) UnmarshalText(text []byte) error {
+	level, err := NewLevel(string(text))
+	if err != nil {
+		return err
+	}
+	self.SetLevel(level)
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// slogger-cat reads slogger text logs, filters them and writes them
+// out as text, JSON or logfmt.  Logs from several files are merged by
+// timestamp.  Compressed rotated logs are decompressed, and with -set
+// each argument names the active log of a rotated set, all of which is
+// read in order.  For example:
+//
+//	slogger-cat -set -level warn -since 2015-03-04T05:00:00 -format json /var/log/agent.log
+package main
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+	"github.com/mongodb/slogger/v2/slogger/rolling_file_appender"
+
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// filter holds the conditions a log must meet to be written.  Empty
+// fields match everything.
+type filter struct {
+	level    slogger.Level
+	prefix   string
+	since    time.Time
+	until    time.Time
+	file     string
+	funcName string
+	regexp   *regexp.Regexp
+}
+
+func (self *filter) matches(log *slogger.ParsedLog) bool {
+	if log.Level < self.level {
+		return false
+	}
+	if self.prefix != "" && !strings.HasPrefix(log.Prefix, self.prefix) {
+		return false
+	}
+	if !self.since.IsZero() && log.Timestamp.Before(self.since) {
+		return false
+	}
+	if !self.until.IsZero() && !log.Timestamp.Before(self.until) {
+		return false
+	}
+	if self.file != "" && !matchFilename(self.file, log.Filename) {
+		return false
+	}
+	if self.funcName != "" {
+		if matched, _ := path.Match(self.funcName, log.FuncName); !matched {
+			return false
+		}
+	}
+	if self.regexp != nil && !self.regexp.MatchString(log.Message) {
+		return false
+	}
+	return true
+}
+
+// matchFilename matches a pattern without a slash against the base
+// name of filename only.
+func matchFilename(pattern, filename string) bool {
+	if !strings.Contains(pattern, "/") {
+		filename = path.Base(filename)
+	}
+	matched, _ := path.Match(pattern, filename)
+	return matched
+}
+
+// timeLayouts are the layouts accepted by -since and -until.  Times
+// without a UTC offset are local.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", value)
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("slogger-cat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: slogger-cat [options] [file ...]\n\n")
+		fmt.Fprintf(stderr, "Reads standard input if no files are given.\n\n")
+		flags.PrintDefaults()
+	}
+
+	levelStr := flags.String("level", "trace", "minimum level of logs to write")
+	prefix := flags.String("prefix", "", "only write logs whose prefix starts with this")
+	sinceStr := flags.String("since", "", "only write logs at or after this time")
+	untilStr := flags.String("until", "", "only write logs before this time")
+	file := flags.String("file", "", "only write logs from source files matching this glob")
+	funcName := flags.String("func", "", "only write logs from functions matching this glob")
+	regexpStr := flags.String("regexp", "", "only write logs whose message matches this regular expression")
+	format := flags.String("format", "text", "output format: text, json or logfmt")
+	set := flags.Bool("set", false, "read the rotated logs of each file before the file itself")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	fail := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, "slogger-cat: "+format+"\n", args...)
+		return 2
+	}
+
+	var err error
+	logFilter := &filter{prefix: *prefix, file: *file, funcName: *funcName}
+
+	if logFilter.level, err = slogger.NewLevel(*levelStr); err != nil {
+		return fail("%v", err)
+	}
+	if *sinceStr != "" {
+		if logFilter.since, err = parseTime(*sinceStr); err != nil {
+			return fail("-since: %v", err)
+		}
+	}
+	if *untilStr != "" {
+		if logFilter.until, err = parseTime(*untilStr); err != nil {
+			return fail("-until: %v", err)
+		}
+	}
+	if *regexpStr != "" {
+		if logFilter.regexp, err = regexp.Compile(*regexpStr); err != nil {
+			return fail("-regexp: %v", err)
+		}
+	}
+
+	writeLog, ok := writers[*format]
+	if !ok {
+		return fail("unknown format %q", *format)
+	}
+
+	inputs, err := openInputs(flags.Args(), *set, stdin)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer func() {
+		for _, input := range inputs {
+			input.Close()
+		}
+	}()
+
+	out := bufio.NewWriter(stdout)
+	err = merge(inputs, func(log *slogger.ParsedLog) error {
+		if !logFilter.matches(log) {
+			return nil
+		}
+		return writeLog(out, log)
+	})
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "slogger-cat: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+type input struct {
+	name    string
+	reader  io.ReadCloser
+	scanner *slogger.LogScanner
+}
+
+func (self *input) Close() error {
+	return self.reader.Close()
+}
+
+func openInputs(filenames []string, set bool, stdin io.Reader) ([]*input, error) {
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	inputs := make([]*input, 0, len(filenames))
+	for _, filename := range filenames {
+		var reader io.ReadCloser
+		var err error
+
+		switch {
+		case filename == "-":
+			reader = io.NopCloser(stdin)
+		case set:
+			reader, err = rolling_file_appender.OpenSet(filename)
+		default:
+			reader, err = rolling_file_appender.OpenLog(filename)
+		}
+		if err != nil {
+			for _, input := range inputs {
+				input.Close()
+			}
+			return nil, err
+		}
+
+		inputs = append(inputs, &input{
+			name:    filename,
+			reader:  reader,
+			scanner: slogger.NewLogScanner(reader),
+		})
+	}
+	return inputs, nil
+}
+
+// merge passes the logs of all inputs to f in timestamp order.  Each
+// input is assumed to be in timestamp order already; ties go to the
+// input named first.
+func merge(inputs []*input, f func(*slogger.ParsedLog) error) error {
+	heads := make([]*slogger.ParsedLog, len(inputs))
+	advance := func(i int) error {
+		heads[i] = nil
+		if inputs[i].scanner.Scan() {
+			heads[i] = inputs[i].scanner.Log()
+			return nil
+		}
+		if err := inputs[i].scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %v", inputs[i].name, err)
+		}
+		return nil
+	}
+
+	for i := range inputs {
+		if err := advance(i); err != nil {
+			return err
+		}
+	}
+
+	for {
+		next := -1
+		for i, head := range heads {
+			if head != nil && (next < 0 || head.Timestamp.Before(heads[next].Timestamp)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return nil
+		}
+
+		if err := f(heads[next]); err != nil {
+			return err
+		}
+		if err := advance(next); err != nil {
+			return err
+		}
+	}
+}
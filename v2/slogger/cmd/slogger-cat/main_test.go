@@ -0,0 +1,150 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const appLog = `[2015/03/04 05:00:01.000] [app.info] [main.go:main:10] starting
+[2015/03/04 05:00:03.000] [app.db.warn] [db.go:Connect:20] [3] connection refused
+at db.go:20
+at main.go:12
+[2015/03/04 05:00:05.000] [app.error] [main.go:main:30] giving up
+`
+
+const agentLog = `[2015/03/04 05:00:02.000] [agent.debug] [agent.go:Poll:5] polling
+[2015/03/04 05:00:04.000] [agent.warn] [agent.go:Poll:7] slow poll
+`
+
+func writeTestLogs(test *testing.T) (appPath, agentPath string) {
+	dir, err := ioutil.TempDir("", "slogger-cat")
+	if err != nil {
+		test.Fatal(err)
+	}
+	test.Cleanup(func() { os.RemoveAll(dir) })
+
+	appPath = filepath.Join(dir, "app.log")
+	if err = ioutil.WriteFile(appPath, []byte(appLog), 0644); err != nil {
+		test.Fatal(err)
+	}
+
+	agentPath = filepath.Join(dir, "agent.log.gz")
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(agentLog))
+	writer.Close()
+	if err = ioutil.WriteFile(agentPath, compressed.Bytes(), 0644); err != nil {
+		test.Fatal(err)
+	}
+
+	return appPath, agentPath
+}
+
+func runCat(test *testing.T, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if status := run(args, strings.NewReader(""), &stdout, &stderr); status != 0 {
+		test.Fatalf("run(%v) exited with %d: %s", args, status, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestMerge(test *testing.T) {
+	appPath, agentPath := writeTestLogs(test)
+
+	output := runCat(test, appPath, agentPath)
+	expected := []string{"starting", "polling", "connection refused", "slow poll", "giving up"}
+	last := -1
+	for _, message := range expected {
+		index := strings.Index(output, message)
+		if index <= last {
+			test.Fatalf("Expected %q after offset %d in:\n%s", message, last, output)
+		}
+		last = index
+	}
+
+	if !strings.Contains(output, "[3] connection refused\nat db.go:20\nat main.go:12\n") {
+		test.Errorf("Multi-line message was not kept intact:\n%s", output)
+	}
+}
+
+func TestFilters(test *testing.T) {
+	appPath, agentPath := writeTestLogs(test)
+
+	cases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-level", "warn"}, []string{"connection refused", "slow poll", "giving up"}},
+		{[]string{"-prefix", "app.db"}, []string{"connection refused"}},
+		{[]string{"-since", "2015/03/04 05:00:02", "-until", "2015-03-04T05:00:04"}, []string{"polling", "connection refused"}},
+		{[]string{"-file", "agent.go", "-func", "P*"}, []string{"polling", "slow poll"}},
+		{[]string{"-regexp", "^giving|poll$"}, []string{"slow poll", "giving up"}},
+	}
+
+	for _, c := range cases {
+		output := runCat(test, append(c.args, appPath, agentPath)...)
+		lines := 0
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			if strings.HasPrefix(line, "[") {
+				lines++
+			}
+		}
+		if lines != len(c.expected) {
+			test.Errorf("%v: expected %d logs, got:\n%s", c.args, len(c.expected), output)
+		}
+		for _, message := range c.expected {
+			if !strings.Contains(output, message) {
+				test.Errorf("%v: expected %q in:\n%s", c.args, message, output)
+			}
+		}
+	}
+}
+
+func TestFormats(test *testing.T) {
+	appPath, _ := writeTestLogs(test)
+
+	output := runCat(test, "-format", "json", "-prefix", "app.db", appPath)
+	if !strings.Contains(output, `"level":"warn"`) || !strings.Contains(output, `"errorCode":3`) ||
+		!strings.Contains(output, `"message":"connection refused\nat db.go:20\nat main.go:12"`) {
+		test.Errorf("Unexpected JSON output: %s", output)
+	}
+
+	output = runCat(test, "-format", "logfmt", "-prefix", "app.db", appPath)
+	if !strings.Contains(output, "level=warn prefix=app.db file=db.go func=Connect line=20 error_code=3 ") ||
+		!strings.Contains(output, `msg="connection refused\nat db.go:20\nat main.go:12"`) {
+		test.Errorf("Unexpected logfmt output: %s", output)
+	}
+}
+
+func TestBadArguments(test *testing.T) {
+	for _, args := range [][]string{
+		{"-level", "loud"},
+		{"-format", "xml"},
+		{"-since", "yesterday"},
+		{"no-such-file"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if status := run(args, strings.NewReader(""), &stdout, &stderr); status == 0 {
+			test.Errorf("Expected %v to fail", args)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var writers = map[string]func(io.Writer, *slogger.ParsedLog) error{
+	"text":   writeText,
+	"json":   writeJSON,
+	"logfmt": writeLogfmt,
+}
+
+// writeText writes log the way slogger formatted it in the first
+// place.
+func writeText(out io.Writer, log *slogger.ParsedLog) error {
+	var text string
+	if log.HasTimezone {
+		text = slogger.FormatLogWithTimezone(log.Log())
+	} else {
+		text = slogger.FormatLog(log.Log())
+	}
+	_, err := io.WriteString(out, text)
+	return err
+}
+
+// writeJSON writes log the way debug_handler and sse_appender encode
+// logs.
+func writeJSON(out io.Writer, log *slogger.ParsedLog) error {
+	return json.NewEncoder(out).Encode(slogger.NewJSONLog(log.Log()))
+}
+
+func writeLogfmt(out io.Writer, log *slogger.ParsedLog) error {
+	fields := []string{
+		"time=" + log.Timestamp.Format(time.RFC3339Nano),
+		"level=" + log.Level.Type(),
+		"prefix=" + logfmtValue(log.Prefix),
+		"file=" + logfmtValue(log.Filename),
+		"func=" + logfmtValue(log.FuncName),
+		"line=" + strconv.Itoa(log.Line),
+	}
+	if log.ErrorCode != slogger.NoErrorCode {
+		fields = append(fields, "error_code="+strconv.Itoa(int(log.ErrorCode)))
+	}
+	fields = append(fields, "msg="+logfmtValue(log.Message))
+
+	_, err := io.WriteString(out, strings.Join(fields, " ")+"\n")
+	return err
+}
+
+// logfmtValue quotes value if it is empty or contains anything that
+// would end it early.
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =") || strconv.Quote(value) != `"`+value+`"` {
+		return strconv.Quote(value)
+	}
+	return value
+}
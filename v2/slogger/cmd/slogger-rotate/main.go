@@ -0,0 +1,189 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// slogger-rotate applies RollingFileAppender retention and compression
+// policies to the rotated logs in directories without a running
+// process, for example after a service crashed or was decommissioned.
+// Active log files are never touched.  Rotated logs compressed and
+// encrypted with a builtin compressor are recognized, and removed
+// like any other, but never decrypted.  For example:
+//
+//	slogger-rotate -dry-run -max-rotated-logs 10 -max-age 30d -compress /var/log/agent
+package main
+
+import (
+	"github.com/mongodb/slogger/v2/slogger/rolling_file_appender"
+
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], time.Now(), os.Stdout, os.Stderr))
+}
+
+var compressors = map[string]func(level int) rolling_file_appender.Compressor{
+	"gzip":  rolling_file_appender.GzipCompressor,
+	"zlib":  rolling_file_appender.ZlibCompressor,
+	"flate": rolling_file_appender.FlateCompressor,
+}
+
+// parseAge is time.ParseDuration that also accepts a number of days,
+// such as 30d.
+func parseAge(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// parseSize parses a number of bytes with an optional K, M, G or T
+// suffix (powers of 1024).
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	number := strings.TrimSuffix(strings.ToUpper(value), "B")
+	if n := len(number); n > 0 {
+		if shift := strings.IndexByte("KMGT", number[n-1]); shift >= 0 {
+			multiplier <<= 10 * uint(shift+1)
+			number = number[:n-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * multiplier, nil
+}
+
+func run(args []string, now time.Time, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("slogger-rotate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: slogger-rotate [options] directory ...\n\n")
+		flags.PrintDefaults()
+	}
+
+	maxRotatedLogs := flags.Int("max-rotated-logs", 0, "number of newest rotated logs to keep per log file (0 keeps all)")
+	maxAgeStr := flags.String("max-age", "", "remove rotated logs rotated longer ago, e.g. 36h or 30d")
+	maxTotalSizeStr := flags.String("max-total-size", "", "remove the oldest rotated logs of a log file beyond this total size, e.g. 500M")
+	compress := flags.Bool("compress", false, "compress rotated logs")
+	compressorName := flags.String("compressor", "gzip", "compression format: gzip, zlib or flate")
+	compressionLevel := flags.Int("compression-level", -1, "compression level (-1 is the format's default)")
+	maxUncompressedLogs := flags.Int("max-uncompressed-logs", 0, "number of newest rotated logs to leave uncompressed")
+	dryRun := flags.Bool("dry-run", false, "only list what would be removed or compressed")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	fail := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, "slogger-rotate: "+format+"\n", args...)
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	policy := rolling_file_appender.RetentionPolicy{
+		MaxRotatedLogs:      *maxRotatedLogs,
+		MaxUncompressedLogs: *maxUncompressedLogs,
+	}
+
+	var err error
+	if *maxAgeStr != "" {
+		if policy.MaxAge, err = parseAge(*maxAgeStr); err != nil {
+			return fail("-max-age: %v", err)
+		}
+	}
+	if *maxTotalSizeStr != "" {
+		if policy.MaxTotalSize, err = parseSize(*maxTotalSizeStr); err != nil {
+			return fail("-max-total-size: %v", err)
+		}
+	}
+	// only the suffixes of these are needed to recognize encrypted
+	// logs, so they have no keys
+	for _, newCompressor := range compressors {
+		rolling_file_appender.RegisterCompressor(rolling_file_appender.EncryptingCompressor(
+			newCompressor(-1),
+			rolling_file_appender.EncryptionKeys{},
+		))
+	}
+
+	if *compress {
+		newCompressor, ok := compressors[*compressorName]
+		if !ok {
+			return fail("unknown compressor %q", *compressorName)
+		}
+		policy.Compressor = newCompressor(*compressionLevel)
+		if _, err = policy.Compressor.NewWriter(io.Discard); err != nil {
+			return fail("-compression-level: %v", err)
+		}
+	}
+
+	action := func(verb string) string {
+		if *dryRun {
+			return "would " + verb
+		}
+		return verb
+	}
+
+	status := 0
+	reportErr := func(err error) {
+		fmt.Fprintf(stderr, "slogger-rotate: %v\n", err)
+		status = 1
+	}
+
+	for _, dir := range flags.Args() {
+		logFiles, err := rolling_file_appender.LogFilesInDirectory(dir)
+		if err != nil {
+			reportErr(err)
+			continue
+		}
+
+		for _, logFile := range logFiles {
+			plan, err := rolling_file_appender.PlanRetention(logFile, policy, now)
+			if err != nil {
+				reportErr(err)
+				continue
+			}
+
+			for _, filename := range plan.Remove {
+				fmt.Fprintf(stdout, "%s %s\n", action("remove"), filename)
+			}
+			for _, filename := range plan.Compress {
+				fmt.Fprintf(stdout, "%s %s\n", action("compress"), filename)
+			}
+
+			if !*dryRun {
+				for _, err := range plan.Apply() {
+					reportErr(err)
+				}
+			}
+		}
+	}
+
+	return status
+}
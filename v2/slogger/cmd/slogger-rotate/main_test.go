@@ -0,0 +1,186 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2015, 3, 10, 0, 0, 0, 0, time.Local)
+
+func createTestDir(test *testing.T) string {
+	dir, err := ioutil.TempDir("", "slogger-rotate")
+	if err != nil {
+		test.Fatal(err)
+	}
+	test.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]int{
+		"app.log":                       10,
+		"app.log.2015-03-01T00-00-00":   100,
+		"app.log.2015-03-07T00-00-00":   100,
+		"app.log.2015-03-08T00-00-00":   100,
+		"app.log.2015-03-09T00-00-00":   100,
+		"app.log.2015-03-09T00-00-00-1": 100,
+		"app.log.backup":                100,
+		"other.log.2015-03-02T00-00-00": 100,
+		"other.log.2015-03-09T12-00-00": 100,
+		"notes.txt":                     100,
+	}
+	for name, size := range files {
+		contents := strings.Repeat("This is a log line\n", size/19+1)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0640); err != nil {
+			test.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func listDir(test *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		test.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runRotate(test *testing.T, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if status := run(args, testNow, &stdout, &stderr); status != 0 {
+		test.Fatalf("run(%v) exited with %d: %s", args, status, stderr.String())
+	}
+	return stdout.String()
+}
+
+func assertNames(test *testing.T, got []string, expected ...string) {
+	sort.Strings(got)
+	sort.Strings(expected)
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		test.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDryRun(test *testing.T) {
+	dir := createTestDir(test)
+	before := listDir(test, dir)
+
+	output := runRotate(test, "-dry-run", "-max-rotated-logs", "2", "-compress", dir)
+
+	var actions []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		actions = append(actions, strings.Replace(line, dir+string(filepath.Separator), "", 1))
+	}
+	assertNames(test, actions,
+		"would remove app.log.2015-03-01T00-00-00",
+		"would remove app.log.2015-03-07T00-00-00",
+		"would remove app.log.2015-03-08T00-00-00",
+		"would compress app.log.2015-03-09T00-00-00",
+		"would compress app.log.2015-03-09T00-00-00-1",
+		"would compress other.log.2015-03-02T00-00-00",
+		"would compress other.log.2015-03-09T12-00-00",
+	)
+	assertNames(test, listDir(test, dir), before...)
+}
+
+func TestRetention(test *testing.T) {
+	dir := createTestDir(test)
+
+	runRotate(test, "-max-age", "2d", "-compress", "-max-uncompressed-logs", "1", dir)
+	assertNames(test, listDir(test, dir),
+		"app.log",
+		"app.log.2015-03-08T00-00-00.gz",
+		"app.log.2015-03-09T00-00-00.gz",
+		"app.log.2015-03-09T00-00-00-1",
+		"app.log.backup",
+		"other.log.2015-03-09T12-00-00",
+		"notes.txt",
+	)
+
+	info, err := os.Stat(filepath.Join(dir, "app.log.2015-03-08T00-00-00.gz"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		test.Errorf("Expected compressed log to keep mode 0640, got %v", info.Mode().Perm())
+	}
+
+	// compressed logs count towards the total size with their
+	// compressed size
+	runRotate(test, "-max-total-size", "300", dir)
+	assertNames(test, listDir(test, dir),
+		"app.log",
+		"app.log.2015-03-09T00-00-00.gz",
+		"app.log.2015-03-09T00-00-00-1",
+		"app.log.backup",
+		"other.log.2015-03-09T12-00-00",
+		"notes.txt",
+	)
+}
+
+func TestEncryptedLogs(test *testing.T) {
+	dir := createTestDir(test)
+	for _, name := range []string{"app.log.2015-03-02T00-00-00.gz.enc", "app.log.2015-03-09T06-00-00.zz.enc"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("SLOGENC1"), 0640); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	runRotate(test, "-max-rotated-logs", "2", "-compress", dir)
+	assertNames(test, listDir(test, dir),
+		"app.log",
+		"app.log.2015-03-09T00-00-00-1.gz",
+		"app.log.2015-03-09T06-00-00.zz.enc",
+		"app.log.backup",
+		"other.log.2015-03-02T00-00-00.gz",
+		"other.log.2015-03-09T12-00-00.gz",
+		"notes.txt",
+	)
+}
+
+func TestBadArguments(test *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-max-age", "soon", "."},
+		{"-max-total-size", "big", "."},
+		{"-compress", "-compressor", "xz", "."},
+		{"-compress", "-compression-level", "42", "."},
+	} {
+		var stdout, stderr bytes.Buffer
+		if status := run(args, testNow, &stdout, &stderr); status == 0 {
+			test.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+func TestParseSize(test *testing.T) {
+	for value, expected := range map[string]int64{"10": 10, "2K": 2048, "3mb": 3 << 20, "1G": 1 << 30} {
+		size, err := parseSize(value)
+		if err != nil || size != expected {
+			test.Errorf("parseSize(%q) = %d, %v; expected %d", value, size, err, expected)
+		}
+	}
+}
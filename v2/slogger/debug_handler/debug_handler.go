@@ -0,0 +1,296 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debug_handler provides an http.Handler for an admin port
+// that shows recent logs kept by a RingAppender, shows how the
+// appenders of loggers are wired together and gets or sets the levels
+// of level filters at runtime.  Mount it under a prefix with
+// http.StripPrefix:
+//
+//	mux.Handle("/debug/logs/", http.StripPrefix("/debug/logs", debug_handler.New(ring, logger)))
+//
+// It serves:
+//
+//	GET /logs       recent logs, filtered by the level, prefix and text
+//	                query parameters, as text or with format=json
+//	GET /appenders  the appender tree of each logger as JSON
+//	GET /levels     the appenders whose level can be set, as JSON
+//	POST /levels    sets the level of the appender with the id and
+//	                level query parameters
+package debug_handler
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+	"github.com/mongodb/slogger/v2/slogger/ring_appender"
+
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// appenderWrapper is implemented by appenders that pass logs on to
+// another appender, such as slogger.FilterAppender.
+type appenderWrapper interface {
+	WrappedAppender() slogger.Appender
+}
+
+// leveled is implemented by appenders with a level that can be
+// changed, such as RetainingLevelFilterAppender.
+type leveled interface {
+	Level() slogger.Level
+	SetLevel(level slogger.Level)
+}
+
+// thresholded is implemented by appenders that have a level that can
+// be changed only in some configurations, as LevelThreshold reports.
+type thresholded interface {
+	LevelThreshold() (slogger.Level, bool)
+	SetLevelThreshold(level slogger.Level) bool
+}
+
+type Handler struct {
+	ring    *ring_appender.RingAppender
+	loggers []*slogger.Logger
+}
+
+// New returns a Handler showing the logs kept by ring, which may be
+// nil, and the appenders of loggers.
+func New(ring *ring_appender.RingAppender, loggers ...*slogger.Logger) *Handler {
+	return &Handler{ring, loggers}
+}
+
+func (self *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/logs":
+		self.onlyGet(self.serveLogs)(w, r)
+	case "/appenders":
+		self.onlyGet(self.serveAppenders)(w, r)
+	case "/levels":
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			self.serveLevels(w, r)
+		case http.MethodPost, http.MethodPut:
+			self.setLevel(w, r)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (self *Handler) onlyGet(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		f(w, r)
+	}
+}
+
+func (self *Handler) serveLogs(w http.ResponseWriter, r *http.Request) {
+	if self.ring == nil {
+		http.Error(w, "no ring appender configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	level := slogger.TRACE
+	if levelStr := query.Get("level"); levelStr != "" {
+		var err error
+		if level, err = slogger.NewLevel(levelStr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			http.Error(w, "invalid limit: "+limitStr, http.StatusBadRequest)
+			return
+		}
+	}
+	prefix := query.Get("prefix")
+	text := query.Get("text")
+
+	logs := make([]*slogger.Log, 0)
+	for _, log := range self.ring.FilterByLevel(level) {
+		if !strings.HasPrefix(log.Prefix, prefix) {
+			continue
+		}
+		if text != "" && !strings.Contains(log.Message(), text) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	if limit > 0 && len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+
+	switch query.Get("format") {
+	case "", "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		format := slogger.GetFormatLogFunc()
+		for _, log := range logs {
+			fmt.Fprint(w, format(log))
+		}
+	case "json":
+		jsonLogs := make([]slogger.JSONLog, 0, len(logs))
+		for _, log := range logs {
+			jsonLogs = append(jsonLogs, slogger.NewJSONLog(log))
+		}
+		writeJSON(w, http.StatusOK, jsonLogs)
+	default:
+		http.Error(w, "unknown format: "+query.Get("format"), http.StatusBadRequest)
+	}
+}
+
+// appenderNode describes an appender.  Its ID is the path of indexes
+// from the logger down to it, such as "0.1.0" for what the second
+// appender of the first logger wraps.
+type appenderNode struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Level    string          `json:"level,omitempty"`
+	Children []*appenderNode `json:"children,omitempty"`
+
+	appender slogger.Appender
+}
+
+type loggerNode struct {
+	Prefix    string          `json:"prefix"`
+	Appenders []*appenderNode `json:"appenders"`
+}
+
+func (self *Handler) tree() []*loggerNode {
+	loggers := make([]*loggerNode, 0, len(self.loggers))
+	for i, logger := range self.loggers {
+		node := &loggerNode{Prefix: logger.Prefix, Appenders: make([]*appenderNode, 0, len(logger.Appenders))}
+		for j, appender := range logger.Appenders {
+			node.Appenders = append(node.Appenders, describe(fmt.Sprintf("%d.%d", i, j), appender))
+		}
+		loggers = append(loggers, node)
+	}
+	return loggers
+}
+
+func describe(id string, appender slogger.Appender) *appenderNode {
+	node := &appenderNode{
+		ID:       id,
+		Type:     fmt.Sprintf("%T", appender),
+		appender: appender,
+	}
+	if level, ok := levelOf(appender); ok {
+		node.Level = level.Type()
+	}
+	if wrapper, ok := appender.(appenderWrapper); ok {
+		if wrapped := wrapper.WrappedAppender(); wrapped != nil {
+			node.Children = []*appenderNode{describe(id+".0", wrapped)}
+		}
+	}
+	return node
+}
+
+func levelOf(appender slogger.Appender) (slogger.Level, bool) {
+	switch appender := appender.(type) {
+	case leveled:
+		return appender.Level(), true
+	case thresholded:
+		return appender.LevelThreshold()
+	}
+	return slogger.OFF, false
+}
+
+func setLevelOf(appender slogger.Appender, level slogger.Level) bool {
+	switch appender := appender.(type) {
+	case leveled:
+		appender.SetLevel(level)
+		return true
+	case thresholded:
+		return appender.SetLevelThreshold(level)
+	}
+	return false
+}
+
+func (self *Handler) serveAppenders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, self.tree())
+}
+
+// leveledNodes returns the nodes of appenders whose level can be set.
+func (self *Handler) leveledNodes() []*appenderNode {
+	nodes := make([]*appenderNode, 0)
+	var walk func(node *appenderNode)
+	walk = func(node *appenderNode) {
+		if node.Level != "" {
+			nodes = append(nodes, &appenderNode{ID: node.ID, Type: node.Type, Level: node.Level, appender: node.appender})
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+
+	for _, logger := range self.tree() {
+		for _, node := range logger.Appenders {
+			walk(node)
+		}
+	}
+	return nodes
+}
+
+func (self *Handler) serveLevels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, self.leveledNodes())
+}
+
+func (self *Handler) setLevel(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.Form.Get("id")
+	level, err := slogger.NewLevel(r.Form.Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, node := range self.leveledNodes() {
+		if node.ID != id {
+			continue
+		}
+		if !setLevelOf(node.appender, level) {
+			http.Error(w, "appender "+id+" has no level", http.StatusConflict)
+			return
+		}
+		node.Level = level.Type()
+		writeJSON(w, http.StatusOK, node)
+		return
+	}
+
+	http.Error(w, "no appender with a level has id "+id, http.StatusNotFound)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug_handler
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+	"github.com/mongodb/slogger/v2/slogger/retaining_level_filter_appender"
+	"github.com/mongodb/slogger/v2/slogger/ring_appender"
+
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type setup struct {
+	handler   *Handler
+	ring      *ring_appender.RingAppender
+	logger    *slogger.Logger
+	retaining *retaining_level_filter_appender.RetainingLevelFilterAppender
+	filter    *slogger.FilterAppender
+}
+
+func newSetup() *setup {
+	ring := ring_appender.New(100)
+	filter := slogger.LevelFilter(slogger.INFO, ring)
+	retaining := retaining_level_filter_appender.New(
+		"category", 10, slogger.WARN,
+		slogger.NewStringAppender(&bytes.Buffer{}),
+	)
+	logger := &slogger.Logger{
+		Prefix:    "debug",
+		Appenders: []slogger.Appender{filter, retaining},
+	}
+
+	return &setup{New(ring, logger), ring, logger, retaining, filter}
+}
+
+func request(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func TestLogs(test *testing.T) {
+	s := newSetup()
+	s.logger.Logf(slogger.DEBUG, "hidden")
+	s.logger.Logf(slogger.INFO, "first info")
+	s.logger.Logf(slogger.WARN, "a warning")
+	s.logger.Logf(slogger.INFO, "second info")
+	(&slogger.Logger{Prefix: "other", Appenders: []slogger.Appender{s.ring}}).Logf(slogger.ERROR, "other error")
+
+	check := func(target, expected string) {
+		response := request(s.handler, http.MethodGet, target)
+		if response.Code != http.StatusOK {
+			test.Errorf("%s: expected status 200, got %d: %s", target, response.Code, response.Body)
+			return
+		}
+		var logs []slogger.JSONLog
+		if err := json.Unmarshal(response.Body.Bytes(), &logs); err != nil {
+			test.Errorf("%s: %v", target, err)
+			return
+		}
+		messages := make([]string, 0, len(logs))
+		for _, log := range logs {
+			messages = append(messages, log.Message)
+		}
+		if got := strings.Join(messages, ","); got != expected {
+			test.Errorf("%s: expected %q, got %q", target, expected, got)
+		}
+	}
+
+	check("/logs?format=json", "first info,a warning,second info,other error")
+	check("/logs?format=json&level=warn", "a warning,other error")
+	check("/logs?format=json&prefix=deb", "first info,a warning,second info")
+	check("/logs?format=json&text=info", "first info,second info")
+	check("/logs?format=json&limit=2", "second info,other error")
+
+	response := request(s.handler, http.MethodGet, "/logs?level=error")
+	if !strings.Contains(response.Body.String(), "other error") ||
+		strings.Contains(response.Body.String(), "a warning") {
+		test.Errorf("Unexpected text output: %s", response.Body)
+	}
+
+	context := slogger.NewContext()
+	context.Add("request", 1234)
+	s.logger.LogfWithContext(slogger.INFO, "with context", context)
+	response = request(s.handler, http.MethodGet, "/logs?format=json&text=context")
+	var logs []slogger.JSONLog
+	if err := json.Unmarshal(response.Body.Bytes(), &logs); err != nil {
+		test.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Context["request"] != "1234" {
+		test.Errorf("Expected the context of the log, got %s", response.Body)
+	}
+
+	for _, target := range []string{"/logs?level=loud", "/logs?limit=x", "/logs?format=xml"} {
+		if response := request(s.handler, http.MethodGet, target); response.Code != http.StatusBadRequest {
+			test.Errorf("%s: expected status 400, got %d", target, response.Code)
+		}
+	}
+}
+
+func TestAppenders(test *testing.T) {
+	s := newSetup()
+
+	response := request(s.handler, http.MethodGet, "/appenders")
+	var loggers []*loggerNode
+	if err := json.Unmarshal(response.Body.Bytes(), &loggers); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(loggers) != 1 || loggers[0].Prefix != "debug" || len(loggers[0].Appenders) != 2 {
+		test.Fatalf("Unexpected tree: %s", response.Body)
+	}
+
+	filter := loggers[0].Appenders[0]
+	if filter.ID != "0.0" || filter.Type != "*slogger.FilterAppender" || filter.Level != "info" {
+		test.Errorf("Unexpected filter node: %+v", filter)
+	}
+	if len(filter.Children) != 1 || filter.Children[0].ID != "0.0.0" ||
+		filter.Children[0].Type != "*ring_appender.RingAppender" {
+		test.Errorf("Unexpected children of filter: %s", response.Body)
+	}
+
+	retaining := loggers[0].Appenders[1]
+	if retaining.ID != "0.1" || retaining.Level != "warn" || len(retaining.Children) != 1 {
+		test.Errorf("Unexpected retaining node: %s", response.Body)
+	}
+}
+
+func TestLevels(test *testing.T) {
+	s := newSetup()
+
+	response := request(s.handler, http.MethodGet, "/levels")
+	var nodes []*appenderNode
+	if err := json.Unmarshal(response.Body.Bytes(), &nodes); err != nil {
+		test.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].ID != "0.0" || nodes[1].ID != "0.1" {
+		test.Fatalf("Unexpected levels: %s", response.Body)
+	}
+
+	response = request(s.handler, http.MethodPost, "/levels?id=0.0&level=debug")
+	if response.Code != http.StatusOK {
+		test.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body)
+	}
+	if level, _ := s.filter.LevelThreshold(); level != slogger.DEBUG {
+		test.Errorf("Expected filter level debug, got %v", level)
+	}
+	s.logger.Logf(slogger.DEBUG, "now shown")
+	if s.ring.Len() != 1 {
+		test.Errorf("Expected the debug log to reach the ring")
+	}
+
+	response = request(s.handler, http.MethodPut, "/levels?id=0.1&level=error")
+	if response.Code != http.StatusOK {
+		test.Fatalf("Expected status 200, got %d: %s", response.Code, response.Body)
+	}
+	if s.retaining.Level() != slogger.ERROR {
+		test.Errorf("Expected retaining level error, got %v", s.retaining.Level())
+	}
+
+	for target, status := range map[string]int{
+		"/levels?id=0.0.0&level=info": http.StatusNotFound,
+		"/levels?id=0.0&level=loud":   http.StatusBadRequest,
+	} {
+		if response := request(s.handler, http.MethodPost, target); response.Code != status {
+			test.Errorf("%s: expected status %d, got %d", target, status, response.Code)
+		}
+	}
+
+	if response := request(s.handler, http.MethodDelete, "/levels"); response.Code != http.StatusMethodNotAllowed {
+		test.Errorf("Expected status 405, got %d", response.Code)
+	}
+	if response := request(s.handler, http.MethodGet, "/nothing"); response.Code != http.StatusNotFound {
+		test.Errorf("Expected status 404, got %d", response.Code)
+	}
+}
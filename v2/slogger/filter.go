@@ -23,3 +23,11 @@ func TurboLevelFilter(threshold Level) func(Level, string, ...interface{}) bool
 		return level >= threshold
 	}
 }
+
+// TurboAtomicLevelFilter is like TurboLevelFilter, but the level can
+// be changed at any time through threshold.
+func TurboAtomicLevelFilter(threshold *AtomicLevel) func(Level, string, ...interface{}) bool {
+	return func(level Level, messageFmt string, args ...interface{}) bool {
+		return threshold.Enabled(level)
+	}
+}
@@ -35,3 +35,45 @@ func TestTurboFilterLevels(test *testing.T) {
 		test.Errorf("Expected lesser level to halt evaluation")
 	}
 }
+
+func TestAtomicLevel(test *testing.T) {
+	level := NewAtomicLevel(WARN)
+	turbo := TurboAtomicLevelFilter(level)
+	counter := &countingAppender{}
+	logger := &Logger{
+		Prefix:       "agent",
+		Appenders:    []Appender{AtomicLevelFilter(level, counter)},
+		TurboFilters: []TurboFilter{turbo},
+	}
+
+	if turbo(INFO, "Evaluation should halt") {
+		test.Errorf("Expected lesser level to halt evaluation")
+	}
+	logger.Logf(INFO, "hidden")
+
+	if err := level.UnmarshalText([]byte("Debug")); err != nil {
+		test.Fatal(err)
+	}
+	if !turbo(INFO, "Evaluation should continue") {
+		test.Errorf("Expected greater level to continue evaluation after the change")
+	}
+	logger.Logf(INFO, "shown")
+	if counter.count != 1 {
+		test.Errorf("Expected one log to pass the filters. Received: %d", counter.count)
+	}
+
+	if err := level.UnmarshalText([]byte("loud")); err == nil {
+		test.Errorf("Expected an error for an unknown level")
+	}
+	if level.Level() != DEBUG {
+		test.Errorf("Expected an unknown level to leave DEBUG, got %v", level.Level())
+	}
+	if text, _ := level.MarshalText(); string(text) != "debug" {
+		test.Errorf("Expected debug, got %s", text)
+	}
+
+	var zero AtomicLevel
+	if !zero.Enabled(TRACE) {
+		test.Errorf("Expected the zero AtomicLevel to be TRACE")
+	}
+}
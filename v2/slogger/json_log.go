@@ -0,0 +1,57 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import (
+	"fmt"
+	"time"
+)
+
+// JSONLog is how a Log is encoded as JSON, for example by
+// debug_handler and sse_appender.  Context values are formatted with
+// fmt.Sprint.
+type JSONLog struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Prefix    string            `json:"prefix"`
+	Level     string            `json:"level"`
+	Filename  string            `json:"file"`
+	FuncName  string            `json:"func"`
+	Line      int               `json:"line"`
+	ErrorCode int               `json:"errorCode,omitempty"`
+	Message   string            `json:"message"`
+	Context   map[string]string `json:"context,omitempty"`
+}
+
+func NewJSONLog(log *Log) JSONLog {
+	result := JSONLog{
+		Timestamp: log.Timestamp,
+		Prefix:    log.Prefix,
+		Level:     log.Level.Type(),
+		Filename:  log.Filename,
+		FuncName:  log.FuncName,
+		Line:      log.Line,
+		ErrorCode: int(log.ErrorCode),
+		Message:   log.Message(),
+	}
+	if log.Context != nil && log.Context.Len() > 0 {
+		result.Context = make(map[string]string)
+		for _, key := range log.Context.Keys() {
+			if value, found := log.Context.Get(key); found {
+				result.Context[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	return result
+}
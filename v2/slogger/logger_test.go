@@ -134,6 +134,34 @@ func TestFilterOff(test *testing.T) {
 	}
 }
 
+func TestSetLevelThreshold(test *testing.T) {
+	counter := &countingAppender{}
+	filter := LevelFilter(WARN, counter)
+	logger := &Logger{
+		Prefix:    "agent.OplogTail",
+		Appenders: []Appender{filter},
+	}
+
+	logger.Logf(INFO, "%d", 0)
+	if level, ok := filter.LevelThreshold(); !ok || level != WARN {
+		test.Errorf("Expected threshold WARN, got %v (%v)", level, ok)
+	}
+	if !filter.SetLevelThreshold(INFO) {
+		test.Fatal("SetLevelThreshold() failed on a level filter")
+	}
+	logger.Logf(INFO, "%d", 1)
+
+	if counter.count != 1 {
+		test.Errorf("Expected one log to pass through the filter to the appender. Received: %d",
+			counter.count)
+	}
+
+	other := &FilterAppender{Appender: counter, Filter: func(*Log) bool { return true }}
+	if _, ok := other.LevelThreshold(); ok || other.SetLevelThreshold(INFO) {
+		test.Error("Expected a plain FilterAppender to have no level threshold")
+	}
+}
+
 func TestStacktrace(test *testing.T) {
 	stacktrace := NewStackError("").Stacktrace
 	if match, _ := regexp.MatchString("^at v2/slogger/logger_test.go:\\d+", stacktrace[0]); match == false {
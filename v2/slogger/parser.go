@@ -0,0 +1,268 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedLog is a log read back from the output of FormatLog or
+// FormatLogWithTimezone.
+type ParsedLog struct {
+	Timestamp time.Time
+	// HasTimezone is true if the timestamp carried a UTC offset, as
+	// written by FormatLogWithTimezone.
+	HasTimezone bool
+	Prefix      string
+	Level       Level
+	Filename    string
+	FuncName    string
+	Line        int
+	ErrorCode   ErrorCode
+	// Message holds every line of a multi-line message, such as
+	// one logged with Stackf, separated by newlines.
+	Message string
+}
+
+// Log returns a Log that formats back to the parsed text.
+func (self *ParsedLog) Log() *Log {
+	return &Log{
+		Prefix:     self.Prefix,
+		Level:      self.Level,
+		ErrorCode:  self.ErrorCode,
+		Filename:   self.Filename,
+		FuncName:   self.FuncName,
+		Line:       self.Line,
+		Timestamp:  self.Timestamp,
+		MessageFmt: "%s",
+		Args:       []interface{}{self.Message},
+	}
+}
+
+// logLineRegExp matches the first line of a formatted log.  The
+// filename is matched lazily and the function name may not contain
+// colons, so Windows paths like C:/foo.go are handled.  A message
+// that itself starts with a bracketed number is indistinguishable
+// from an error code.
+var logLineRegExp = regexp.MustCompile(
+	`^\[([^\]]+)\] ` +
+		`\[(.*?)\.(trace|debug|info|warn|error|fatal|off|off\?)\] ` +
+		`\[(.*?):([^:\]]*):(-?\d+)\] ` +
+		`(?:\[(\d{1,3})\] )?` +
+		`(.*)$`,
+)
+
+const (
+	logTimestampLayout             = "2006/01/02 15:04:05.000"
+	logTimestampWithTimezoneLayout = "2006-01-02T15:04:05.000"
+)
+
+type ParseError struct {
+	Line   string
+	Reason string
+}
+
+func (self ParseError) Error() string {
+	return fmt.Sprintf("Cannot parse log line (%s): %s", self.Reason, self.Line)
+}
+
+func IsParseError(err error) bool {
+	_, ok := err.(ParseError)
+	return ok
+}
+
+// ParseLog parses a single log formatted by FormatLog or
+// FormatLogWithTimezone.  Lines after the first are taken to be part
+// of the message.  Timestamps without a UTC offset are taken to be in
+// the local time zone.
+func ParseLog(text string) (*ParsedLog, error) {
+	text = strings.TrimSuffix(text, "\n")
+	lines := strings.SplitN(text, "\n", 2)
+
+	log, err := parseLogLine(lines[0], time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > 1 {
+		log.Message += "\n" + lines[1]
+	}
+	return log, nil
+}
+
+// LogScanner reads logs formatted by FormatLog or
+// FormatLogWithTimezone one at a time, like a bufio.Scanner.  A line
+// that does not start a new log is appended to the message of the
+// log before it.  Lines before the first log are skipped.
+//
+//	scanner := slogger.NewLogScanner(file)
+//	for scanner.Scan() {
+//		log := scanner.Log()
+//		...
+//	}
+//	if err := scanner.Err(); err != nil {
+//		...
+//	}
+type LogScanner struct {
+	// Location is the time zone of timestamps without a UTC
+	// offset.  It defaults to the local time zone.
+	Location *time.Location
+
+	reader  *bufio.Reader
+	pending *ParsedLog
+	log     *ParsedLog
+	err     error
+	done    bool
+}
+
+func NewLogScanner(reader io.Reader) *LogScanner {
+	return &LogScanner{
+		Location: time.Local,
+		reader:   bufio.NewReader(reader),
+	}
+}
+
+// Scan advances to the next log, which is then available through
+// Log().  It returns false when there are no more logs or an error
+// occurred.
+func (self *LogScanner) Scan() bool {
+	self.log = nil
+
+	for !self.done {
+		line, err := self.reader.ReadString('\n')
+		if err != nil {
+			self.done = true
+			if err != io.EOF {
+				self.err = err
+				return false
+			}
+			if line == "" {
+				break
+			}
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		log, parseErr := parseLogLine(line, self.Location)
+		if parseErr != nil {
+			if self.pending != nil {
+				self.pending.Message += "\n" + line
+			}
+			continue
+		}
+
+		self.log, self.pending = self.pending, log
+		if self.log != nil {
+			return true
+		}
+	}
+
+	self.log, self.pending = self.pending, nil
+	return self.log != nil
+}
+
+// Log returns the log read by the last call to Scan.
+func (self *LogScanner) Log() *ParsedLog {
+	return self.log
+}
+
+// Err returns the first read error, if any.
+func (self *LogScanner) Err() error {
+	return self.err
+}
+
+func parseLogLine(line string, location *time.Location) (*ParsedLog, error) {
+	match := logLineRegExp.FindStringSubmatch(line)
+	if match == nil {
+		return nil, ParseError{line, "not a log line"}
+	}
+
+	timestamp, hasTimezone, err := parseLogTimestamp(match[1], location)
+	if err != nil {
+		return nil, ParseError{line, err.Error()}
+	}
+
+	log := &ParsedLog{
+		Timestamp:   timestamp,
+		HasTimezone: hasTimezone,
+		Prefix:      match[2],
+		Filename:    match[4],
+		FuncName:    match[5],
+		Message:     match[8],
+	}
+
+	if match[3] == "off?" {
+		log.Level = topLevel
+	} else if log.Level, err = NewLevel(match[3]); err != nil {
+		return nil, ParseError{line, err.Error()}
+	}
+
+	if log.Line, err = strconv.Atoi(match[6]); err != nil {
+		return nil, ParseError{line, err.Error()}
+	}
+
+	if match[7] != "" {
+		errorCode, err := strconv.ParseUint(match[7], 10, 8)
+		if err != nil {
+			// not an error code after all
+			log.Message = "[" + match[7] + "] " + log.Message
+		} else {
+			log.ErrorCode = ErrorCode(errorCode)
+		}
+	}
+
+	return log, nil
+}
+
+func parseLogTimestamp(timestamp string, location *time.Location) (time.Time, bool, error) {
+	if !strings.Contains(timestamp, "T") {
+		parsed, err := time.ParseInLocation(logTimestampLayout, timestamp, location)
+		return parsed, false, err
+	}
+
+	offsetIndex := strings.LastIndexAny(timestamp, "+-")
+	if offsetIndex < len(logTimestampWithTimezoneLayout) {
+		return time.Time{}, false, fmt.Errorf("missing UTC offset in %s", timestamp)
+	}
+
+	offset, err := parseUTCOffset(timestamp[offsetIndex:])
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	zone := time.FixedZone("", offset)
+	parsed, err := time.ParseInLocation(logTimestampWithTimezoneLayout, timestamp[:offsetIndex], zone)
+	return parsed, true, err
+}
+
+// parseUTCOffset is the inverse of convertOffsetToString, which
+// writes offsets as hundredths of hours (+0550 for five and a half
+// hours) rather than as hours and minutes.
+func parseUTCOffset(offsetStr string) (int, error) {
+	hundredths, err := strconv.Atoi(offsetStr[1:])
+	if err != nil || len(offsetStr) < 4 {
+		return 0, fmt.Errorf("malformed UTC offset %s", offsetStr)
+	}
+
+	offset := hundredths * 36
+	if offsetStr[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
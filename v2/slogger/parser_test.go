@@ -0,0 +1,112 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogRoundTrip(test *testing.T) {
+	timestamps := []time.Time{
+		time.Date(2015, 3, 4, 5, 6, 7, 890000000, time.UTC),
+		time.Date(2015, 3, 4, 5, 6, 7, 0, time.FixedZone("", -5*3600)),
+		time.Date(2015, 3, 4, 5, 6, 7, 0, time.FixedZone("", 5*3600+1800)),
+		time.Date(2015, 3, 4, 5, 6, 7, 0, time.FixedZone("", 10*3600)),
+	}
+
+	for _, timestamp := range timestamps {
+		log := &Log{
+			Prefix:     "agent.OplogTail",
+			Level:      WARN,
+			ErrorCode:  42,
+			Filename:   "C:/src/oplog.go",
+			FuncName:   "TailOplog",
+			Line:       88,
+			Timestamp:  timestamp,
+			MessageFmt: "Tail [started] on %s",
+			Args:       []interface{}{"backup_test"},
+		}
+
+		parsed, err := ParseLog(FormatLogWithTimezone(log))
+		if err != nil {
+			test.Fatalf("ParseLog() returned an error: %v", err)
+		}
+		if !parsed.HasTimezone || !parsed.Timestamp.Equal(timestamp) {
+			test.Errorf("Expected timestamp %v, got %v", timestamp, parsed.Timestamp)
+		}
+		if FormatLogWithTimezone(parsed.Log()) != FormatLogWithTimezone(log) {
+			test.Errorf("Round trip changed %q to %q", FormatLogWithTimezone(log), FormatLogWithTimezone(parsed.Log()))
+		}
+	}
+}
+
+func TestParseLogFields(test *testing.T) {
+	parsed, err := ParseLog("[2015/03/04 05:06:07.890] [agent.OplogTail.info] [oplog.go:TailOplog:88] Tail started\n")
+	if err != nil {
+		test.Fatalf("ParseLog() returned an error: %v", err)
+	}
+
+	expected := ParsedLog{
+		Timestamp: time.Date(2015, 3, 4, 5, 6, 7, 890000000, time.Local),
+		Prefix:    "agent.OplogTail",
+		Level:     INFO,
+		Filename:  "oplog.go",
+		FuncName:  "TailOplog",
+		Line:      88,
+		Message:   "Tail started",
+	}
+	if *parsed != expected {
+		test.Errorf("Expected %+v, got %+v", expected, *parsed)
+	}
+
+	if _, err = ParseLog("not a log"); !IsParseError(err) {
+		test.Errorf("Expected a ParseError, got %v", err)
+	}
+}
+
+func TestLogScanner(test *testing.T) {
+	input := strings.Join([]string{
+		"garbage before the first log",
+		"[2015/03/04 05:06:07.000] [a.warn] [a.go:A:1] Had an error",
+		"at a.go:1",
+		"at main.go:2",
+		"[2015/03/04 05:06:08.000] [b.error] [b.go:B:2] [300] not an error code",
+		"[2015-03-04T05:06:09.000+0000] [c.debug] [c.go:C:3] [7] last",
+	}, "\n")
+
+	scanner := NewLogScanner(strings.NewReader(input))
+	var logs []*ParsedLog
+	for scanner.Scan() {
+		logs = append(logs, scanner.Log())
+	}
+	if err := scanner.Err(); err != nil {
+		test.Fatalf("scanner.Err() returned %v", err)
+	}
+
+	if len(logs) != 3 {
+		test.Fatalf("Expected 3 logs, got %d", len(logs))
+	}
+	if logs[0].Message != "Had an error\nat a.go:1\nat main.go:2" {
+		test.Errorf("Unexpected multi-line message: %q", logs[0].Message)
+	}
+	if logs[1].ErrorCode != NoErrorCode || logs[1].Message != "[300] not an error code" {
+		test.Errorf("Unexpected log: %+v", logs[1])
+	}
+	if logs[2].ErrorCode != 7 || logs[2].Level != DEBUG || !logs[2].HasTimezone {
+		test.Errorf("Unexpected log: %+v", logs[2])
+	}
+}
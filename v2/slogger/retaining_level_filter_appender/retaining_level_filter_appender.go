@@ -81,6 +81,11 @@ func (self *RetainingLevelFilterAppender) Flush() error {
 	return self.appender.Flush()
 }
 
+// WrappedAppender returns the Appender that logs are passed to.
+func (self *RetainingLevelFilterAppender) WrappedAppender() slogger.Appender {
+	return self.appender
+}
+
 func (self *RetainingLevelFilterAppender) Level() slogger.Level {
 	self.lock.RLock()
 	defer self.lock.RUnlock()
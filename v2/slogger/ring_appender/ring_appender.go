@@ -0,0 +1,190 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// An appender that keeps the most recent logs in memory, either the
+// last N logs or as many of the last logs as fit in N bytes when
+// formatted.  This is useful for crash handlers and debug endpoints
+// that want to show recent activity without reading log files.
+
+package ring_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"io"
+	"sync"
+)
+
+type entry struct {
+	log  *slogger.Log
+	size int
+}
+
+type RingAppender struct {
+	// Only one of the limits is set, the other is 0.  An appender
+	// with neither set keeps nothing.
+	maxLogs  int
+	maxBytes int
+
+	// entries[head:] are the retained logs, oldest first.  The lock
+	// protects entries, head and bytes.
+	entries []entry
+	head    int
+	bytes   int
+	lock    sync.RWMutex
+}
+
+// New returns a RingAppender that keeps the last maxLogs logs.  If
+// maxLogs is 0 or less, no logs are kept.
+func New(maxLogs int) *RingAppender {
+	if maxLogs < 0 {
+		maxLogs = 0
+	}
+	return &RingAppender{
+		maxLogs: maxLogs,
+		entries: make([]entry, 0, maxLogs),
+	}
+}
+
+// NewWithMaxBytes returns a RingAppender that keeps as many of the
+// last logs as fit in maxBytes bytes, as formatted by the format
+// function set with slogger.SetFormatLogFunc.  A log larger than
+// maxBytes is not kept at all, so if maxBytes is 0 or less, no logs
+// are kept.
+func NewWithMaxBytes(maxBytes int) *RingAppender {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	return &RingAppender{maxBytes: maxBytes}
+}
+
+func (self *RingAppender) Append(log *slogger.Log) error {
+	size := 0
+	if self.maxBytes > 0 {
+		size = len(slogger.GetFormatLogFunc()(log))
+		if size > self.maxBytes {
+			return nil
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.maxLogs <= 0 && self.maxBytes <= 0 {
+		return nil
+	}
+
+	for (self.maxLogs > 0 && self.lenLocked() >= self.maxLogs) ||
+		(self.maxBytes > 0 && self.bytes+size > self.maxBytes) {
+		self.dropOldest()
+	}
+
+	// reuse the space of dropped logs rather than growing forever,
+	// moving the retained logs only once there are as many dropped
+	if len(self.entries) == cap(self.entries) && self.head >= len(self.entries)/2 && self.head > 0 {
+		n := copy(self.entries, self.entries[self.head:])
+		for i := n; i < len(self.entries); i++ {
+			self.entries[i] = entry{}
+		}
+		self.entries = self.entries[:n]
+		self.head = 0
+	}
+
+	self.entries = append(self.entries, entry{log, size})
+	self.bytes += size
+	return nil
+}
+
+// dropOldest forgets the oldest log.  The lock should be held when
+// calling this.
+func (self *RingAppender) dropOldest() {
+	self.bytes -= self.entries[self.head].size
+	self.entries[self.head] = entry{}
+	self.head++
+}
+
+func (self *RingAppender) lenLocked() int {
+	return len(self.entries) - self.head
+}
+
+func (self *RingAppender) Flush() error {
+	return nil
+}
+
+// Len returns the number of logs kept.
+func (self *RingAppender) Len() int {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.lenLocked()
+}
+
+// Snapshot returns the logs kept, oldest first.  The logs themselves
+// are shared with the appender and should not be modified.
+func (self *RingAppender) Snapshot() []*slogger.Log {
+	return self.FilterByLevel(slogger.TRACE)
+}
+
+// FilterByLevel returns the logs kept of level or above, oldest
+// first.
+func (self *RingAppender) FilterByLevel(level slogger.Level) []*slogger.Log {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	logs := make([]*slogger.Log, 0, self.lenLocked())
+	for _, entry := range self.entries[self.head:] {
+		if entry.log.Level >= level {
+			logs = append(logs, entry.log)
+		}
+	}
+	return logs
+}
+
+// Iterate calls f with each log kept, oldest first, until f returns
+// false.  It iterates over a snapshot, so f may log to this appender.
+func (self *RingAppender) Iterate(f func(log *slogger.Log) bool) {
+	for _, log := range self.Snapshot() {
+		if !f(log) {
+			return
+		}
+	}
+}
+
+// Clear forgets all logs kept.
+func (self *RingAppender) Clear() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i := range self.entries {
+		self.entries[i] = entry{}
+	}
+	self.entries = self.entries[:0]
+	self.head = 0
+	self.bytes = 0
+}
+
+// WriteTo writes the logs kept to w, oldest first, formatted by the
+// format function set with slogger.SetFormatLogFunc.
+func (self *RingAppender) WriteTo(w io.Writer) (int64, error) {
+	format := slogger.GetFormatLogFunc()
+
+	var written int64
+	for _, log := range self.Snapshot() {
+		n, err := io.WriteString(w, format(log))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ring_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newLog(level slogger.Level, message string) *slogger.Log {
+	return &slogger.Log{
+		Prefix:     "ring",
+		Level:      level,
+		Filename:   "ring_appender_test.go",
+		FuncName:   "newLog",
+		MessageFmt: message,
+	}
+}
+
+func messages(logs []*slogger.Log) string {
+	strs := make([]string, 0, len(logs))
+	for _, log := range logs {
+		strs = append(strs, log.Message())
+	}
+	return strings.Join(strs, ",")
+}
+
+func TestMaxLogs(test *testing.T) {
+	appender := New(3)
+	for i := 0; i < 10; i++ {
+		appender.Append(newLog(slogger.INFO, fmt.Sprint(i)))
+		if i == 1 && messages(appender.Snapshot()) != "0,1" {
+			test.Errorf("Expected 0,1, got %s", messages(appender.Snapshot()))
+		}
+	}
+
+	if got := messages(appender.Snapshot()); got != "7,8,9" {
+		test.Errorf("Expected 7,8,9, got %s", got)
+	}
+	if appender.Len() != 3 {
+		test.Errorf("Expected 3 logs, got %d", appender.Len())
+	}
+}
+
+func TestMaxBytes(test *testing.T) {
+	size := len(slogger.FormatLog(newLog(slogger.INFO, "0")))
+	appender := NewWithMaxBytes(2*size + 1)
+
+	for i := 0; i < 5; i++ {
+		appender.Append(newLog(slogger.INFO, fmt.Sprint(i)))
+	}
+	if got := messages(appender.Snapshot()); got != "3,4" {
+		test.Errorf("Expected 3,4, got %s", got)
+	}
+
+	// pushes out both smaller logs
+	appender.Append(newLog(slogger.INFO, strings.Repeat("x", size)))
+	if appender.Len() != 1 {
+		test.Errorf("Expected 1 log, got %d", appender.Len())
+	}
+
+	// too large to keep at all
+	appender.Append(newLog(slogger.INFO, strings.Repeat("y", 3*size)))
+	if appender.Len() != 1 || strings.HasPrefix(appender.Snapshot()[0].Message(), "y") {
+		test.Errorf("Expected oversized log to be skipped, got %s", messages(appender.Snapshot()))
+	}
+}
+
+func TestNoLimits(test *testing.T) {
+	for _, appender := range []*RingAppender{New(0), New(-1), NewWithMaxBytes(0), NewWithMaxBytes(-1)} {
+		appender.Append(newLog(slogger.INFO, "0"))
+		if appender.Len() != 0 {
+			test.Errorf("Expected no logs to be kept, got %s", messages(appender.Snapshot()))
+		}
+	}
+}
+
+func TestFilterIterateClear(test *testing.T) {
+	appender := New(10)
+	appender.Append(newLog(slogger.DEBUG, "debug"))
+	appender.Append(newLog(slogger.WARN, "warn"))
+	appender.Append(newLog(slogger.ERROR, "error"))
+
+	if got := messages(appender.FilterByLevel(slogger.WARN)); got != "warn,error" {
+		test.Errorf("Expected warn,error, got %s", got)
+	}
+
+	var seen []*slogger.Log
+	appender.Iterate(func(log *slogger.Log) bool {
+		seen = append(seen, log)
+		// logging to the appender from f must not deadlock
+		appender.Append(newLog(slogger.INFO, "during"))
+		return len(seen) < 2
+	})
+	if got := messages(seen); got != "debug,warn" {
+		test.Errorf("Expected debug,warn, got %s", got)
+	}
+
+	var buffer bytes.Buffer
+	if _, err := appender.WriteTo(&buffer); err != nil {
+		test.Fatal(err)
+	}
+	if strings.Count(buffer.String(), "\n") != 5 || !strings.Contains(buffer.String(), "[ring.error]") {
+		test.Errorf("Unexpected output: %s", buffer.String())
+	}
+
+	appender.Clear()
+	if appender.Len() != 0 {
+		test.Errorf("Expected no logs after Clear, got %d", appender.Len())
+	}
+}
+
+func TestConcurrentAppends(test *testing.T) {
+	appender := New(100)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				appender.Append(newLog(slogger.INFO, "concurrent"))
+				appender.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if appender.Len() != 100 {
+		test.Errorf("Expected 100 logs, got %d", appender.Len())
+	}
+}
@@ -0,0 +1,115 @@
+package rolling_file_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"bufio"
+	"io"
+	"os"
+)
+
+// Durability controls when a RollingFileAppender syncs the log file
+// to disk, in addition to when Flush() or Close() is called.
+type Durability int
+
+const (
+	// DurabilityNone never syncs on its own
+	DurabilityNone Durability = iota
+
+	// DurabilityFlushOnInterval flushes the write buffer and syncs
+	// every flush interval
+	DurabilityFlushOnInterval
+
+	// DurabilitySyncOnWarn flushes the write buffer and syncs after
+	// every log of level WARN or higher
+	DurabilitySyncOnWarn
+
+	// DurabilitySyncEveryWrite flushes the write buffer and syncs
+	// after every log
+	DurabilitySyncEveryWrite
+)
+
+// stringWriterAdapter lets a bufio.Writer write to a
+// slogger.StringWriter
+type stringWriterAdapter struct {
+	slogger.StringWriter
+}
+
+func (self stringWriterAdapter) Write(p []byte) (int, error) {
+	return self.WriteString(string(p))
+}
+
+// setFile makes file the log file being written to.  The lock should
+// be held when calling this.
+func (self *RollingFileAppender) setFile(file *os.File) {
+	self.file = file
+	self.stats = fileStats{}
+	self.buffer = nil
+	if file != nil && self.writeBufferSize > 0 {
+		self.buffer = bufio.NewWriterSize(
+			stringWriterAdapter{self.stringWriterCallback(file)},
+			self.writeBufferSize,
+		)
+	}
+}
+
+// writer returns what logs are written to: the write buffer if there
+// is one and otherwise the log file's StringWriter.  The lock should
+// be held when calling this.
+func (self *RollingFileAppender) writer() io.StringWriter {
+	if self.buffer != nil {
+		return self.buffer
+	}
+	return self.stringWriterCallback(self.file)
+}
+
+// flushBuffer writes out the write buffer, if any.  It must be called
+// before syncing or closing the log file.  The lock should be held
+// when calling this.
+func (self *RollingFileAppender) flushBuffer() error {
+	if self.buffer == nil {
+		return nil
+	}
+
+	if err := self.buffer.Flush(); err != nil {
+		return &WriteError{self.absPath, err}
+	}
+	return nil
+}
+
+// flushAndSync flushes the write buffer and syncs the log file.  The
+// lock should be held when calling this.
+func (self *RollingFileAppender) flushAndSync() error {
+	if err := self.flushBuffer(); err != nil {
+		return err
+	}
+
+	if err := self.file.Sync(); err != nil {
+		return &SyncError{self.absPath, err}
+	}
+	return nil
+}
+
+// syncForDurability syncs after log was written to the log file if
+// the durability setting asks for it.  The lock should be held when
+// calling this.
+func (self *RollingFileAppender) syncForDurability(log *slogger.Log) error {
+	switch {
+	case self.durability == DurabilitySyncEveryWrite,
+		self.durability == DurabilitySyncOnWarn && log.Level >= slogger.WARN:
+		return self.flushAndSync()
+	}
+	return nil
+}
+
+// flushOnInterval is run every flush interval
+func (self *RollingFileAppender) flushOnInterval() error {
+	if self.file == nil {
+		return nil
+	}
+
+	if self.durability == DurabilityFlushOnInterval {
+		return self.flushAndSync()
+	}
+	return self.flushBuffer()
+}
@@ -0,0 +1,426 @@
+package rolling_file_appender
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// With hash chaining every line written to the log file ends with a
+// tag holding a SHA-256 hash over the line and the hash of the line
+// before it:
+//
+//	<line>\tsha256=<hex of sha256(previous hash || line)>
+//
+// The chain runs on across rotated logs.  Every time a header is
+// written an anchor line records the hash the chain continues from,
+// so that the oldest log left after old logs were removed can still
+// be verified.  Footers and the state file record the latest hash.
+// VerifyChain checks all of this for a log set.
+
+const chainTagPrefix = "\tsha256="
+
+// chainTagLen is the length of a tag, including the newline
+const chainTagLen = len(chainTagPrefix) + 2*sha256.Size + 1
+
+const (
+	chainAnchorFmt = "Hash chain continues from %s"
+	chainHeadFmt   = "Hash chain head %s"
+)
+
+var chainAnchorRegExp = regexp.MustCompile(`Hash chain continues from ([0-9a-f]{64})$`)
+var chainHeadRegExp = regexp.MustCompile(`Hash chain head ([0-9a-f]{64})$`)
+
+type chainHash [sha256.Size]byte
+
+func (self chainHash) String() string {
+	return hex.EncodeToString(self[:])
+}
+
+func parseChainHash(s string) (chainHash, bool) {
+	var hash chainHash
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != len(hash) {
+		return hash, false
+	}
+	copy(hash[:], decoded)
+	return hash, true
+}
+
+func nextChainHash(previous chainHash, line string) chainHash {
+	hasher := sha256.New()
+	hasher.Write(previous[:])
+	io.WriteString(hasher, line)
+
+	var hash chainHash
+	copy(hash[:], hasher.Sum(nil))
+	return hash
+}
+
+// hashChain tags lines as they are written.  It is protected by the
+// appender's lock.
+type hashChain struct {
+	head chainHash
+}
+
+// tag returns text with each of its lines tagged.  A final line
+// without a newline gets one.
+func (self *hashChain) tag(text string) string {
+	if text == "" {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var tagged strings.Builder
+	tagged.Grow(len(text) + len(lines)*chainTagLen)
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\n")
+		self.head = nextChainHash(self.head, line)
+		tagged.WriteString(line)
+		tagged.WriteString(chainTagPrefix)
+		tagged.WriteString(self.head.String())
+		tagged.WriteByte('\n')
+	}
+	return tagged.String()
+}
+
+// lastChainHead returns the hash the chain of the log file at absPath
+// should continue from: the tag of the last complete line of the log
+// file that has one, or else the head recorded in the state file.  A
+// new chain starts from zero.
+func lastChainHead(absPath string) chainHash {
+	if head, ok := lastChainTag(absPath); ok {
+		return head
+	}
+
+	if state, err := readState(statePathFor(absPath)); err == nil {
+		if head, ok := parseChainHash(state.ChainHead); ok {
+			return head
+		}
+	}
+
+	return chainHash{}
+}
+
+// maxUntaggedLines is how many complete lines without a tag
+// lastChainTag looks past.  A torn write leaves one, which Build()
+// ends with a newline.
+const maxUntaggedLines = 2
+
+// lastChainTag returns the hash in the tag of the last complete line
+// of the log file at absPath that has one.  A line without a newline
+// at the end, as left by a crash during a write, is skipped.
+func lastChainTag(absPath string) (chainHash, bool) {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return chainHash{}, false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return chainHash{}, false
+	}
+
+	end := info.Size()
+	for i := 0; i <= maxUntaggedLines; i++ {
+		newline, err := lastNewline(file, end)
+		if err != nil || newline < 0 {
+			return chainHash{}, false
+		}
+
+		// the tag, including the newline, ends the line
+		if start := newline + 1 - int64(chainTagLen); start >= 0 {
+			buf := make([]byte, chainTagLen)
+			if _, err = file.ReadAt(buf, start); err != nil {
+				return chainHash{}, false
+			}
+			if strings.HasPrefix(string(buf), chainTagPrefix) {
+				if head, ok := splitChainTag(string(buf[:len(buf)-1])); ok {
+					return head, true
+				}
+			}
+		}
+		end = newline
+	}
+	return chainHash{}, false
+}
+
+// lastNewline returns the offset of the last newline in file before
+// end, or -1 if there is none.
+func lastNewline(file *os.File, end int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return -1, err
+		}
+		if index := strings.LastIndexByte(string(chunk), '\n'); index >= 0 {
+			return start + int64(index), nil
+		}
+		end = start
+	}
+	return -1, nil
+}
+
+// endTornLine ends the last line of the log file with a newline if a
+// crash during a write left it without one, so that the chain anchor
+// logged next starts a line of its own.  The lock should be held when
+// calling this.
+func (self *RollingFileAppender) endTornLine() error {
+	if self.curFileSize == 0 {
+		return nil
+	}
+
+	file, err := os.Open(self.absPath)
+	if err != nil {
+		return &OpenError{self.absPath, err}
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err = file.ReadAt(last, self.curFileSize-1); err != nil {
+		return &ReadError{self.absPath, err}
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+
+	n, err := self.writer().WriteString("\n")
+	self.curFileSize += int64(n)
+	if err != nil {
+		return &WriteError{self.absPath, err}
+	}
+	return nil
+}
+
+// splitChainTag returns the hash in the tag at the end of line, which
+// has no newline.
+func splitChainTag(line string) (chainHash, bool) {
+	index := strings.LastIndex(line, chainTagPrefix)
+	if index < 0 {
+		return chainHash{}, false
+	}
+	return parseChainHash(line[index+len(chainTagPrefix):])
+}
+
+// logChainAnchor starts the chain in a new log file, or again after
+// the appender was restarted.  The lock should be held when calling
+// this.
+func (self *RollingFileAppender) logChainAnchor() error {
+	if self.chain == nil {
+		return nil
+	}
+	return self.logSpecialLines("chain", []string{fmt.Sprintf(chainAnchorFmt, self.chain.head)})
+}
+
+// logChainHead records the head of the chain in the footer.  The lock
+// should be held when calling this.
+func (self *RollingFileAppender) logChainHead() error {
+	if self.chain == nil {
+		return nil
+	}
+	return self.logSpecialLines("footer", []string{fmt.Sprintf(chainHeadFmt, self.chain.head)})
+}
+
+// chainHead returns the head of the chain or "" without chaining.  The
+// lock should be held when calling this.
+func (self *RollingFileAppender) chainHead() string {
+	if self.chain == nil {
+		return ""
+	}
+	return self.chain.head.String()
+}
+
+// ChainError describes where VerifyChain found a log set to have been
+// tampered with.
+type ChainError struct {
+	Filename string
+	Line     int64
+	Reason   string
+}
+
+func (self ChainError) Error() string {
+	if self.Line == 0 {
+		return fmt.Sprintf("rolling_file_appender: Hash chain broken in %s: %s", self.Filename, self.Reason)
+	}
+	return fmt.Sprintf(
+		"rolling_file_appender: Hash chain broken at %s:%d: %s",
+		self.Filename,
+		self.Line,
+		self.Reason,
+	)
+}
+
+func IsChainError(err error) bool {
+	_, ok := err.(ChainError)
+	return ok
+}
+
+// ChainVerification describes a log set that passed VerifyChain.
+type ChainVerification struct {
+	Files []string
+	Lines int64
+
+	// Head is the hash of the last line
+	Head string
+
+	// TornLines is the number of lines cut short by a crash during a
+	// write: lines without a newline at the end of a log, and lines
+	// without a tag followed by the anchor logged after a restart.
+	// Such lines are not verified.
+	TornLines int64
+}
+
+// VerifyChain checks the hash chain of the log set of the hash-chained
+// log file at path, as written with WithHashChain().  It returns a
+// ChainError if lines were edited, removed, inserted or reordered, if
+// rotated logs other than the oldest ones were removed, or if lines at
+// the end of the set were removed since the head of the chain was
+// last recorded in the state file (at rotation and on Close()).  The
+// removal of the oldest rotated logs, as done by maxRotatedLogs, is
+// not detected.  A last line without a newline, or a line without a
+// tag followed by a chain anchor, is taken to be a torn write rather
+// than tampering; it is skipped and counted in TornLines.
+func VerifyChain(path string) (*ChainVerification, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	filenames, err := SetFilenames(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var stateHead chainHash
+	haveStateHead := false
+	if state, err := readState(statePathFor(absPath)); err == nil {
+		stateHead, haveStateHead = parseChainHash(state.ChainHead)
+	}
+
+	verification := &ChainVerification{Files: filenames}
+	var head chainHash
+	started := false
+	sawStateHead := false
+
+	// torn is set for a line without a valid tag.  It was left by a
+	// torn write if the next line is an anchor.
+	var torn *ChainError
+
+	for _, filename := range filenames {
+		reader, err := OpenLog(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		buffered := bufio.NewReader(reader)
+		var lineNum int64
+		for {
+			line, readErr := buffered.ReadString('\n')
+			if readErr != nil && readErr != io.EOF {
+				reader.Close()
+				return nil, &ReadError{filename, readErr}
+			}
+			if line == "" {
+				break
+			}
+			lineNum++
+			if readErr == io.EOF {
+				verification.TornLines++
+				break
+			}
+
+			fail := func(reason string) (*ChainVerification, error) {
+				reader.Close()
+				return nil, ChainError{filename, lineNum, reason}
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			index := strings.LastIndex(line, chainTagPrefix)
+			var tagged chainHash
+			ok := index >= 0
+			if ok {
+				tagged, ok = parseChainHash(line[index+len(chainTagPrefix):])
+			}
+			if !ok {
+				if torn != nil {
+					reader.Close()
+					return nil, *torn
+				}
+				reason := "malformed chain tag"
+				if index < 0 {
+					reason = "line is not chained"
+				}
+				torn = &ChainError{filename, lineNum, reason}
+				continue
+			}
+			content := line[:index]
+
+			anchor := chainAnchorRegExp.FindStringSubmatch(content)
+			if torn != nil {
+				if anchor == nil {
+					reader.Close()
+					return nil, *torn
+				}
+				verification.TornLines++
+				torn = nil
+			}
+
+			if anchor != nil {
+				previous, _ := parseChainHash(anchor[1])
+				if !started {
+					head = previous
+					started = true
+				} else if previous != head {
+					return fail("lines or logs before this anchor are missing")
+				}
+			}
+			if !started {
+				return fail("log set does not start with a chain anchor")
+			}
+			if match := chainHeadRegExp.FindStringSubmatch(content); match != nil {
+				if recorded, _ := parseChainHash(match[1]); recorded != head {
+					return fail("chain head in footer does not match")
+				}
+			}
+
+			head = nextChainHash(head, content)
+			if head != tagged {
+				return fail("line was edited, or lines before it were removed, inserted or reordered")
+			}
+			if haveStateHead && head == stateHead {
+				sawStateHead = true
+			}
+			verification.Lines++
+		}
+
+		if err = reader.Close(); err != nil {
+			return nil, &CloseError{filename, err}
+		}
+	}
+
+	if torn != nil {
+		return nil, *torn
+	}
+	if haveStateHead && !sawStateHead {
+		return nil, ChainError{absPath, 0, "chain head in state file was not found; logs were removed from the end"}
+	}
+
+	verification.Head = head.String()
+	return verification, nil
+}
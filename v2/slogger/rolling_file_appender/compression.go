@@ -0,0 +1,187 @@
+package rolling_file_appender
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Compressor compresses rotated log files.  Compressed logs are
+// named by appending Suffix() to the rotated log's filename.
+type Compressor interface {
+	// Suffix returns the filename suffix of compressed logs,
+	// including the leading period (e.g. ".gz").
+	Suffix() string
+
+	// NewWriter returns a WriteCloser that compresses everything
+	// written to it into w.  Close must be called to flush any
+	// buffered data; it does not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns a ReadCloser that decompresses r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+// GzipCompressor returns a Compressor that writes gzip files with a
+// .gz suffix at the given compression level (see compress/gzip).
+func GzipCompressor(level int) Compressor {
+	return gzipCompressor{level}
+}
+
+func (gzipCompressor) Suffix() string {
+	return ".gz"
+}
+
+func (self gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, self.level)
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zlibCompressor struct {
+	level int
+}
+
+// ZlibCompressor returns a Compressor that writes zlib streams with a
+// .zz suffix at the given compression level (see compress/zlib).
+func ZlibCompressor(level int) Compressor {
+	return zlibCompressor{level}
+}
+
+func (zlibCompressor) Suffix() string {
+	return ".zz"
+}
+
+func (self zlibCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, self.level)
+}
+
+func (zlibCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+type flateCompressor struct {
+	level int
+}
+
+// FlateCompressor returns a Compressor that writes raw DEFLATE
+// streams with a .deflate suffix at the given compression level (see
+// compress/flate).
+func FlateCompressor(level int) Compressor {
+	return flateCompressor{level}
+}
+
+func (flateCompressor) Suffix() string {
+	return ".deflate"
+}
+
+func (self flateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, self.level)
+}
+
+func (flateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+var compressorsLock sync.RWMutex
+
+// compressors maps suffixes to the Compressor registered for them
+var compressors = make(map[string]Compressor)
+
+func init() {
+	RegisterCompressor(GzipCompressor(gzip.DefaultCompression))
+	RegisterCompressor(ZlibCompressor(zlib.DefaultCompression))
+	RegisterCompressor(FlateCompressor(flate.DefaultCompression))
+}
+
+// RegisterCompressor makes compressed logs with c's suffix
+// recognizable as rotated logs.  This is needed for them to be
+// counted towards maxRotatedLogs and to be readable.  A Compressor
+// previously registered for the same suffix is replaced.  The
+// builtin compressors are registered already, and Build() registers
+// the Compressor passed to WithCompressor() if none is registered for
+// its suffix.
+func RegisterCompressor(c Compressor) {
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+
+	compressors[c.Suffix()] = c
+	setRotatedTimeRegExp(compressedSuffixesLocked())
+}
+
+// registerBuiltCompressor registers c for Build() unless a Compressor
+// is registered for its suffix already, so that one registered by the
+// caller, such as an EncryptingCompressor with a Lookup function, is
+// kept.  An EncryptingCompressor that cannot decrypt is replaced.
+func registerBuiltCompressor(c Compressor) {
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+
+	if registered, found := compressors[c.Suffix()]; found {
+		encrypting, ok := registered.(encryptingCompressor)
+		if !ok || encrypting.keys.Lookup != nil {
+			return
+		}
+	}
+	compressors[c.Suffix()] = c
+	setRotatedTimeRegExp(compressedSuffixesLocked())
+}
+
+// compressorForFilename returns the Compressor registered for
+// filename's suffix or nil if filename is not a compressed log.
+func compressorForFilename(filename string) Compressor {
+	compressorsLock.RLock()
+	defer compressorsLock.RUnlock()
+
+	// prefer the longest matching suffix in case one registered
+	// suffix ends with another
+	var found Compressor
+	for suffix, c := range compressors {
+		if strings.HasSuffix(filename, suffix) &&
+			(found == nil || len(suffix) > len(found.Suffix())) {
+			found = c
+		}
+	}
+	return found
+}
+
+func isCompressed(filename string) bool {
+	return compressorForFilename(filename) != nil
+}
+
+func compressedSuffixes() []string {
+	compressorsLock.RLock()
+	defer compressorsLock.RUnlock()
+	return compressedSuffixesLocked()
+}
+
+func compressedSuffixesLocked() []string {
+	suffixes := make([]string, 0, len(compressors))
+	for suffix := range compressors {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	return suffixes
+}
+
+// checkCompressor makes sure c can actually create a writer, so that
+// a bad compression level is reported by Build() rather than on the
+// first rotation.
+func checkCompressor(c Compressor) error {
+	w, err := c.NewWriter(ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,182 @@
+package rolling_file_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"os"
+	"sort"
+	"time"
+)
+
+// DiskSpaceGuard configures how a RollingFileAppender degrades when
+// the filesystem holding its logs runs low on free space.  Each
+// threshold is an amount of free space in bytes below which the
+// corresponding measure is taken.  A zero threshold disables that
+// measure.  The measures are cumulative, so thresholds are expected
+// to satisfy CleanupBelow >= ShedBelow >= FallbackBelow.
+type DiskSpaceGuard struct {
+	// CheckInterval is how often free space is checked.  Checks are
+	// made when logging, so nothing is checked while idle.
+	CheckInterval time.Duration
+
+	// CleanupBelow triggers early removal of the oldest rotated
+	// logs, regardless of maxRotatedLogs, until there is enough
+	// free space again or only MinRotatedLogs are left.
+	CleanupBelow   uint64
+	MinRotatedLogs int
+
+	// ShedBelow makes the appender drop logs below ShedLevel (for
+	// example slogger.WARN).
+	ShedBelow uint64
+	ShedLevel slogger.Level
+
+	// FallbackBelow makes the appender send logs to Fallback
+	// instead of the log file.  Logs below ShedLevel are still
+	// dropped.  This measure requires Fallback to be non-nil.
+	FallbackBelow uint64
+	Fallback      slogger.Appender
+
+	// OnTransition, if not nil, is called whenever the appender
+	// moves from one DiskSpaceState to another.  It is called with
+	// the appender locked, so it must not log to the appender.
+	OnTransition func(from, to DiskSpaceState, freeBytes uint64)
+}
+
+// DiskSpaceState is the measure a DiskSpaceGuard is currently taking.
+// Later states include the measures of earlier ones.
+type DiskSpaceState int
+
+const (
+	DiskSpaceOK DiskSpaceState = iota
+	DiskSpaceCleanup
+	DiskSpaceShedding
+	DiskSpaceFallback
+)
+
+func (self DiskSpaceState) String() string {
+	switch self {
+	case DiskSpaceOK:
+		return "ok"
+	case DiskSpaceCleanup:
+		return "cleanup"
+	case DiskSpaceShedding:
+		return "shedding"
+	case DiskSpaceFallback:
+		return "fallback"
+	}
+	return "unknown"
+}
+
+// freeDiskSpace returns the number of bytes available to unprivileged
+// users on the filesystem holding path.  It is a variable so tests can
+// replace it.
+var freeDiskSpace = statfsFreeSpace
+
+func (self *DiskSpaceGuard) stateFor(freeBytes uint64) DiskSpaceState {
+	switch {
+	case self.Fallback != nil && freeBytes < self.FallbackBelow:
+		return DiskSpaceFallback
+	case freeBytes < self.ShedBelow:
+		return DiskSpaceShedding
+	case freeBytes < self.CleanupBelow:
+		return DiskSpaceCleanup
+	}
+	return DiskSpaceOK
+}
+
+// checkDiskSpace updates diskSpaceState if the guard's check interval
+// has passed.  The lock should be held when calling this.
+func (self *RollingFileAppender) checkDiskSpace() {
+	if self.diskSpaceGuard == nil ||
+		time.Since(self.lastDiskSpaceCheck) < self.diskSpaceGuard.CheckInterval {
+		return
+	}
+	self.lastDiskSpaceCheck = time.Now()
+
+	freeBytes, err := freeDiskSpace(self.absPath)
+	if err != nil {
+		self.handleError(&StatError{self.absPath, err})
+		return
+	}
+
+	newState := self.diskSpaceGuard.stateFor(freeBytes)
+	if newState >= DiskSpaceCleanup {
+		self.requestMaintenance()
+	}
+
+	oldState := self.diskSpaceState
+	if newState == oldState {
+		return
+	}
+	self.diskSpaceState = newState
+
+	if self.diskSpaceGuard.OnTransition != nil {
+		self.diskSpaceGuard.OnTransition(oldState, newState, freeBytes)
+	}
+
+	notice := &slogger.Log{
+		Prefix:     "rolling_file_appender",
+		Level:      slogger.WARN,
+		Timestamp:  time.Now(),
+		MessageFmt: "Free disk space for %s is %d bytes. Changed from %v to %v",
+		Args:       []interface{}{self.absPath, freeBytes, oldState, newState},
+	}
+	if err = self.appendGuarded(notice); err != nil {
+		self.handleError(err)
+	}
+}
+
+// appendGuarded writes log to the log file, or drops it or sends it to
+// the fallback appender, depending on diskSpaceState.  The lock
+// should be held when calling this.
+func (self *RollingFileAppender) appendGuarded(log *slogger.Log) error {
+	if self.diskSpaceState >= DiskSpaceShedding && log.Level < self.diskSpaceGuard.ShedLevel {
+		return nil
+	}
+
+	if self.diskSpaceState == DiskSpaceFallback {
+		return self.diskSpaceGuard.Fallback.Append(log)
+	}
+
+	n, err := self.appendSansSizeTracking(log)
+	self.curFileSize += int64(n)
+	if err != nil {
+		return err
+	}
+
+	return self.syncForDurability(log)
+}
+
+// cleanupForDiskSpace removes the oldest rotated logs while free space
+// is below the guard's CleanupBelow threshold.
+func (self *RollingFileAppender) cleanupForDiskSpace() error {
+	if self.diskSpaceGuard == nil || self.diskSpaceGuard.CleanupBelow == 0 {
+		return nil
+	}
+
+	freeBytes, err := freeDiskSpace(self.absPath)
+	if err != nil || freeBytes >= self.diskSpaceGuard.CleanupBelow {
+		return err
+	}
+
+	rotationTimes, err := self.rotationTimeSlice()
+	if err != nil {
+		return &MinorRotationError{err}
+	}
+	sort.Sort(rotationTimes)
+
+	for len(rotationTimes) > self.diskSpaceGuard.MinRotatedLogs {
+		if err = os.Remove(rotationTimes[0].Filename); err != nil {
+			return &MinorRotationError{err}
+		}
+		self.forgetRotationReason(rotationTimes[0].Filename)
+		rotationTimes = rotationTimes[1:]
+
+		freeBytes, err = freeDiskSpace(self.absPath)
+		if err != nil || freeBytes >= self.diskSpaceGuard.CleanupBelow {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,339 @@
+package rolling_file_appender
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncryptionKeys provides the AES keys (16, 24 or 32 bytes long) used
+// to encrypt and decrypt rotated logs.  Keys are identified by an ID
+// stored in each encrypted file, so that keys can be rotated while
+// older files stay readable.
+type EncryptionKeys struct {
+	// Current returns the key to encrypt new files with and its ID.
+	// It is called once per file, and once by Build() to check that a
+	// key can be had.
+	Current func() (keyID string, key []byte, err error)
+
+	// Lookup returns the key with the given ID for decryption.  It
+	// may be nil if files are only ever written.
+	Lookup func(keyID string) (key []byte, err error)
+}
+
+// Encrypted files are made of a header followed by chunks:
+//
+//	header: encryptionMagic, key ID length (1 byte), key ID,
+//	        nonce prefix (7 random bytes)
+//	chunk:  length of the sealed chunk (4 bytes, big-endian, the top
+//	        bit marking the final chunk), sealed chunk
+//
+// Each chunk holds up to encryptionChunkSize bytes sealed with
+// AES-GCM.  Its nonce is the nonce prefix, the chunk's index (4
+// bytes, big-endian) and a byte that is 1 for the final chunk, so
+// that chunks cannot be reordered and a truncated file is detected.
+// The header is authenticated as additional data with every chunk.
+const (
+	encryptionMagic      = "SLOGENC1"
+	encryptionChunkSize  = 64 * 1024
+	encryptionPrefixSize = 7
+	encryptionFinalFlag  = 1 << 31
+	encryptedSuffix      = ".enc"
+)
+
+var (
+	errEncryptedTruncated = errors.New("encrypted log is truncated")
+	errEncryptedTrailing  = errors.New("encrypted log has data after its final chunk")
+)
+
+type encryptingCompressor struct {
+	inner Compressor
+	keys  EncryptionKeys
+}
+
+// EncryptingCompressor returns a Compressor that encrypts what inner
+// compresses with AES-GCM, appending .enc to inner's suffix.  To read
+// encrypted logs, for example with OpenSet, register an
+// EncryptingCompressor with a Lookup function using
+// RegisterCompressor.
+func EncryptingCompressor(inner Compressor, keys EncryptionKeys) Compressor {
+	return encryptingCompressor{inner, keys}
+}
+
+func (self encryptingCompressor) Suffix() string {
+	return self.inner.Suffix() + encryptedSuffix
+}
+
+func (self encryptingCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if self.keys.Current == nil {
+		return nil, errors.New("no function for the current encryption key")
+	}
+	keyID, key, err := self.keys.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter, err := newEncryptWriter(w, keyID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	compresser, err := self.inner.NewWriter(encrypter)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptingWriteCloser{compresser, encrypter}, nil
+}
+
+func (self encryptingCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	if self.keys.Lookup == nil {
+		return nil, errors.New("no function to look up encryption keys")
+	}
+
+	decrypter, err := newDecryptReader(r, self.keys.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	return self.inner.NewReader(decrypter)
+}
+
+// encryptingWriteCloser closes the compressor, then the encrypter
+// underneath it.
+type encryptingWriteCloser struct {
+	io.WriteCloser
+	encrypter *encryptWriter
+}
+
+func (self *encryptingWriteCloser) Close() error {
+	if err := self.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return self.encrypter.Close()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, final bool) []byte {
+	nonce := make([]byte, encryptionPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixSize:], index)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	index   uint32
+	pending []byte
+	closed  bool
+}
+
+func newEncryptWriter(w io.Writer, keyID string, key []byte) (*encryptWriter, error) {
+	if len(keyID) > 255 {
+		return nil, fmt.Errorf("encryption key ID %q is longer than 255 bytes", keyID)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, encryptionPrefixSize)
+	if _, err = io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(encryptionMagic)
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+	header.Write(prefix)
+
+	if _, err = w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:       w,
+		aead:    aead,
+		header:  header.Bytes(),
+		prefix:  prefix,
+		pending: make([]byte, 0, encryptionChunkSize),
+	}, nil
+}
+
+func (self *encryptWriter) Write(p []byte) (int, error) {
+	if self.closed {
+		return 0, errors.New("write to closed encrypter")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// keep a full chunk pending so the final chunk is never
+		// empty unless the whole file is
+		if len(self.pending) == encryptionChunkSize {
+			if err := self.writeChunk(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(self.pending[len(self.pending):encryptionChunkSize], p)
+		self.pending = self.pending[:len(self.pending)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (self *encryptWriter) writeChunk(final bool) error {
+	if self.index == ^uint32(0) {
+		return errors.New("encrypted log is too large")
+	}
+
+	nonce := chunkNonce(self.prefix, self.index, final)
+	sealed := self.aead.Seal(nil, nonce, self.pending, self.header)
+	self.index++
+	self.pending = self.pending[:0]
+
+	length := uint32(len(sealed))
+	if final {
+		length |= encryptionFinalFlag
+	}
+	var lengthBytes [4]byte
+	binary.BigEndian.PutUint32(lengthBytes[:], length)
+
+	if _, err := self.w.Write(lengthBytes[:]); err != nil {
+		return err
+	}
+	_, err := self.w.Write(sealed)
+	return err
+}
+
+// Close writes the final chunk.  It does not close the underlying
+// writer.
+func (self *encryptWriter) Close() error {
+	if self.closed {
+		return nil
+	}
+	self.closed = true
+	return self.writeChunk(true)
+}
+
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint32
+	plain  []byte
+	sealed []byte
+	done   bool
+}
+
+func newDecryptReader(r io.Reader, lookup func(string) ([]byte, error)) (*decryptReader, error) {
+	header := make([]byte, len(encryptionMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading encryption header: %v", err)
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("not an encrypted log")
+	}
+
+	rest := make([]byte, int(header[len(encryptionMagic)])+encryptionPrefixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("reading encryption header: %v", err)
+	}
+	header = append(header, rest...)
+	keyID := string(rest[:len(rest)-encryptionPrefixSize])
+
+	key, err := lookup(keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:      r,
+		aead:   aead,
+		header: header,
+		prefix: rest[len(rest)-encryptionPrefixSize:],
+		sealed: make([]byte, 0, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (self *decryptReader) Read(p []byte) (int, error) {
+	for len(self.plain) == 0 {
+		if self.done {
+			return 0, io.EOF
+		}
+		if err := self.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, self.plain)
+	self.plain = self.plain[n:]
+	return n, nil
+}
+
+func (self *decryptReader) readChunk() error {
+	var lengthBytes [4]byte
+	if _, err := io.ReadFull(self.r, lengthBytes[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errEncryptedTruncated
+		}
+		return err
+	}
+
+	length := binary.BigEndian.Uint32(lengthBytes[:])
+	final := length&encryptionFinalFlag != 0
+	length &^= encryptionFinalFlag
+	if length > uint32(cap(self.sealed)) {
+		return errors.New("encrypted log chunk is too large")
+	}
+
+	sealed := self.sealed[:length]
+	if _, err := io.ReadFull(self.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errEncryptedTruncated
+		}
+		return err
+	}
+
+	nonce := chunkNonce(self.prefix, self.index, final)
+	plain, err := self.aead.Open(nil, nonce, sealed, self.header)
+	if err != nil {
+		return errors.New("encrypted log was tampered with or the key is wrong")
+	}
+	if final {
+		// anything after the final chunk was appended to the file
+		var extra [1]byte
+		if n, err := io.ReadFull(self.r, extra[:]); n > 0 {
+			return errEncryptedTrailing
+		} else if err != io.EOF {
+			return err
+		}
+	}
+	self.index++
+	self.plain = plain
+	self.done = final
+	return nil
+}
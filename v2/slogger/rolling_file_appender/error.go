@@ -22,6 +22,24 @@ func IsCloseError(err error) bool {
 	return ok
 }
 
+type LockError struct {
+	Filename string
+	Err      error
+}
+
+func (self LockError) Error() string {
+	return fmt.Sprintf(
+		"rolling_file_appender: Failed to lock %s: %s",
+		self.Filename,
+		self.Err.Error(),
+	)
+}
+
+func IsLockError(err error) bool {
+	_, ok := err.(LockError)
+	return ok
+}
+
 type MinorRotationError struct {
 	Err error
 }
@@ -64,6 +82,24 @@ func IsOpenError(err error) bool {
 	return ok
 }
 
+type ReadError struct {
+	Filename string
+	Err      error
+}
+
+func (self ReadError) Error() string {
+	return fmt.Sprintf(
+		"rolling_file_appender: Failed to read %s: %s",
+		self.Filename,
+		self.Err.Error(),
+	)
+}
+
+func IsReadError(err error) bool {
+	_, ok := err.(ReadError)
+	return ok
+}
+
 type RenameError struct {
 	OldFilename string
 	NewFilename string
@@ -84,6 +120,26 @@ func IsRenameError(err error) bool {
 	return ok
 }
 
+type SymlinkError struct {
+	Filename string
+	Target   string
+	Err      error
+}
+
+func (self SymlinkError) Error() string {
+	return fmt.Sprintf(
+		"rolling_file_appender: Failed to link %s to %s: %s",
+		self.Filename,
+		self.Target,
+		self.Err.Error(),
+	)
+}
+
+func IsSymlinkError(err error) bool {
+	_, ok := err.(SymlinkError)
+	return ok
+}
+
 type WriteError struct {
 	Filename string
 	Err      error
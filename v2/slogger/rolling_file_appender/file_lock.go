@@ -0,0 +1,55 @@
+package rolling_file_appender
+
+import (
+	"path/filepath"
+)
+
+// withFileLock calls f while holding the lock on the hidden lock file
+// shared by all processes logging to absPath.  If file locking is not
+// enabled, f is simply called.  The appender lock should be held when
+// calling this.
+func (self *RollingFileAppender) withFileLock(f func() error) error {
+	if !self.fileLocking {
+		return f()
+	}
+
+	lock, err := lockFile(self.lockPath(), self.fileOptions)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	return f()
+}
+
+// withMaintenanceLock calls f while holding the lock that keeps
+// processes sharing absPath from removing or compressing the same
+// rotated logs at once.  It is separate from the lock taken for
+// rotation so that a long compression does not hold up rotation, and
+// with it logging, in other processes.  The appender lock need not be
+// held when calling this.
+func (self *RollingFileAppender) withMaintenanceLock(f func()) error {
+	if !self.fileLocking {
+		f()
+		return nil
+	}
+
+	lock, err := lockFile(self.maintenanceLockPath(), self.fileOptions)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	f()
+	return nil
+}
+
+func (self *RollingFileAppender) lockPath() string {
+	newBase := ".slogger-lock-" + filepath.Base(self.absPath)
+	return filepath.Join(filepath.Dir(self.absPath), newBase)
+}
+
+func (self *RollingFileAppender) maintenanceLockPath() string {
+	newBase := ".slogger-maintenance-lock-" + filepath.Base(self.absPath)
+	return filepath.Join(filepath.Dir(self.absPath), newBase)
+}
@@ -0,0 +1,40 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package rolling_file_appender
+
+import (
+	"os"
+	"syscall"
+)
+
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile blocks until it holds an exclusive flock(2) on path,
+// creating path if needed.
+func lockFile(path string, options fileOptions) (*fileLock, error) {
+	file, err := options.openFile(path, os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		return nil, OpenError{path, err}
+	}
+
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, LockError{path, err}
+	}
+
+	return &fileLock{file}, nil
+}
+
+func (self *fileLock) unlock() error {
+	// closing the file releases the lock
+	return self.file.Close()
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package rolling_file_appender
+
+// fileLock is a no-op on platforms without flock(2)
+type fileLock struct{}
+
+func lockFile(path string, options fileOptions) (*fileLock, error) {
+	return &fileLock{}, nil
+}
+
+func (*fileLock) unlock() error {
+	return nil
+}
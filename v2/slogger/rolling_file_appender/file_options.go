@@ -0,0 +1,102 @@
+package rolling_file_appender
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileOptions controls the permissions and ownership of the files
+// and directories the appender creates: active, rotated, compressed,
+// state and lock files.
+type fileOptions struct {
+	// mode is the permission of created files.  If it is zero,
+	// files are created with 0666 less the umask.  Otherwise the
+	// mode is set exactly, regardless of the umask.
+	mode os.FileMode
+
+	// uid and gid files are chowned to if chown is true.  Either
+	// can be -1 to leave it unchanged.
+	chown bool
+	uid   int
+	gid   int
+
+	// createDirs makes the appender create missing parent
+	// directories with dirMode.
+	createDirs bool
+	dirMode    os.FileMode
+}
+
+func (self fileOptions) perm() os.FileMode {
+	if self.mode == 0 {
+		return 0666 // umask applies to perms
+	}
+	return self.mode
+}
+
+// openFile is os.OpenFile using the configured mode and ownership
+func (self fileOptions) openFile(path string, flag int) (*os.File, error) {
+	file, err := os.OpenFile(path, flag, self.perm())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = self.apply(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// apply sets the configured mode and ownership on file
+func (self fileOptions) apply(file *os.File) error {
+	if self.mode != 0 {
+		if err := file.Chmod(self.mode); err != nil {
+			return err
+		}
+	}
+
+	if self.chown {
+		if err := file.Chown(self.uid, self.gid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// makeParentDirs creates the missing parent directories of path, if
+// enabled, setting their mode and ownership.
+func (self fileOptions) makeParentDirs(path string) error {
+	if !self.createDirs {
+		return nil
+	}
+
+	// find the directories that are missing, deepest first
+	var missing []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+		if err := os.Mkdir(dir, self.dirMode); err != nil && !os.IsExist(err) {
+			return err
+		}
+		if err := os.Chmod(dir, self.dirMode); err != nil {
+			return err
+		}
+		if self.chown {
+			if err := os.Chown(dir, self.uid, self.gid); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package rolling_file_appender
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultFollowPollInterval = 100 * time.Millisecond
+
+// FollowOptions configures a Follower.
+type FollowOptions struct {
+	// PollInterval is how long the Follower waits before checking
+	// for new data once it has caught up.  Zero means 100ms.
+	PollInterval time.Duration
+
+	// FromEnd makes the Follower skip what is already in the log
+	// file when it is opened, like tail -f.  Otherwise the Follower
+	// starts at the beginning of the file.
+	FromEnd bool
+}
+
+// Follower reads the active log file of a RollingFileAppender as it
+// grows, like tail -F.  When the file is rotated (renamed, deleted or
+// replaced by a file with another inode) the Follower reads the rest
+// of the old file and then continues with the new one.  When the file
+// is truncated the Follower starts over at its beginning.
+//
+// Read and ReadLine block until data is available or the Follower is
+// closed, after which they return io.EOF.
+type Follower struct {
+	absPath      string
+	pollInterval time.Duration
+
+	// lock protects the fields below; it is held while reading
+	lock     sync.Mutex
+	file     *os.File
+	fileInfo os.FileInfo
+	offset   int64
+	reader   *bufio.Reader
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// Follow returns a Follower for the log file at path.  The file does
+// not need to exist yet.  The returned Follower must be closed.
+func Follow(path string, options FollowOptions) (*Follower, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pollInterval := options.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultFollowPollInterval
+	}
+
+	self := &Follower{
+		absPath:      absPath,
+		pollInterval: pollInterval,
+		stopCh:       make(chan struct{}),
+	}
+	self.reader = bufio.NewReader(followReader{self})
+
+	if err = self.openFile(); err != nil {
+		return nil, err
+	}
+
+	if options.FromEnd && self.file != nil {
+		offset, err := self.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			self.file.Close()
+			return nil, &StatError{absPath, err}
+		}
+		self.offset = offset
+	}
+
+	return self, nil
+}
+
+func (self *Follower) Read(p []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reader.Read(p)
+}
+
+// ReadLine returns the next complete line, without its trailing
+// newline, waiting for it to be written if necessary.
+func (self *Follower) ReadLine() (string, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	line, err := self.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Close stops the Follower, unblocking any Read or ReadLine in
+// progress.  It is safe to call Close more than once.
+func (self *Follower) Close() error {
+	self.stopOnce.Do(func() {
+		close(self.stopCh)
+	})
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.file == nil {
+		return nil
+	}
+	err := self.file.Close()
+	self.file = nil
+	if err != nil {
+		return &CloseError{self.absPath, err}
+	}
+	return nil
+}
+
+// followReader lets the Follower's bufio.Reader read from the
+// underlying files without taking the lock, which Read and ReadLine
+// already hold.
+type followReader struct {
+	follower *Follower
+}
+
+func (self followReader) Read(p []byte) (int, error) {
+	return self.follower.readFiles(p)
+}
+
+// readFiles reads from the current file, moving on to a new file
+// after rotation and waiting when there is nothing to read.  The lock
+// should be held when calling this.
+func (self *Follower) readFiles(p []byte) (int, error) {
+	for {
+		if self.stopped() {
+			return 0, io.EOF
+		}
+
+		if self.file != nil {
+			n, err := self.file.Read(p)
+			self.offset += int64(n)
+			if n > 0 {
+				return n, nil
+			}
+			if err != nil && err != io.EOF {
+				return 0, &ReadError{self.absPath, err}
+			}
+		}
+
+		// we caught up, so look for rotation and truncation
+		switched, err := self.checkFile()
+		if err != nil {
+			return 0, err
+		}
+		if switched {
+			continue
+		}
+
+		select {
+		case <-self.stopCh:
+		case <-time.After(self.pollInterval):
+		}
+	}
+}
+
+// checkFile reports whether the Follower switched to another file or
+// to the beginning of the current one.  The lock should be held when
+// calling this.
+func (self *Follower) checkFile() (bool, error) {
+	pathInfo, err := os.Stat(self.absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// keep reading the old file until a new one appears
+			return false, nil
+		}
+		return false, &StatError{self.absPath, err}
+	}
+
+	if self.file == nil {
+		return true, self.openFile()
+	}
+
+	if !os.SameFile(self.fileInfo, pathInfo) {
+		// Data may have been written to the old file between our
+		// last read and the rotation, so drain it before moving on.
+		if self.drained() {
+			self.file.Close()
+			return true, self.openFile()
+		}
+		return true, nil
+	}
+
+	if pathInfo.Size() < self.offset {
+		if _, err = self.file.Seek(0, io.SeekStart); err != nil {
+			return false, &ReadError{self.absPath, err}
+		}
+		self.offset = 0
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// drained reports whether everything has been read from the current
+// file.  The lock should be held when calling this.
+func (self *Follower) drained() bool {
+	info, err := self.file.Stat()
+	return err != nil || info.Size() <= self.offset
+}
+
+// openFile opens the file at absPath if it exists.  The lock should
+// be held when calling this.
+func (self *Follower) openFile() error {
+	self.file = nil
+	self.fileInfo = nil
+	self.offset = 0
+
+	file, err := os.Open(self.absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return &OpenError{self.absPath, err}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return &StatError{self.absPath, err}
+	}
+
+	self.file = file
+	self.fileInfo = info
+	return nil
+}
+
+func (self *Follower) stopped() bool {
+	select {
+	case <-self.stopCh:
+		return true
+	default:
+		return false
+	}
+}
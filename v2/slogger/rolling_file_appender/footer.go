@@ -0,0 +1,59 @@
+package rolling_file_appender
+
+import (
+	"strings"
+	"time"
+)
+
+// FileStats describes a log file that is being closed.  It is passed
+// to the footer generator.  The statistics only cover what the
+// appender wrote to the file since opening it, including the header.
+type FileStats struct {
+	// Path is the path of the log file before it is rotated
+	Path string
+
+	// Lines and Bytes are the number of lines and bytes written
+	Lines int64
+	Bytes int64
+
+	// FirstTimestamp and LastTimestamp are the timestamps of the
+	// first and last logs written.  They are zero if nothing was
+	// written.
+	FirstTimestamp time.Time
+	LastTimestamp  time.Time
+
+	// Reason is why the file is being closed.  It is
+	// RotationReasonNone if the appender is being closed.
+	Reason RotationReason
+}
+
+type fileStats struct {
+	lines          int64
+	bytes          int64
+	firstTimestamp time.Time
+	lastTimestamp  time.Time
+}
+
+func (self *fileStats) record(written string, timestamp time.Time) {
+	if len(written) == 0 {
+		return
+	}
+
+	self.lines += int64(strings.Count(written, "\n"))
+	self.bytes += int64(len(written))
+	if self.firstTimestamp.IsZero() {
+		self.firstTimestamp = timestamp
+	}
+	self.lastTimestamp = timestamp
+}
+
+func (self *fileStats) snapshot(path string, reason RotationReason) FileStats {
+	return FileStats{
+		Path:           path,
+		Lines:          self.lines,
+		Bytes:          self.bytes,
+		FirstTimestamp: self.firstTimestamp,
+		LastTimestamp:  self.lastTimestamp,
+		Reason:         reason,
+	}
+}
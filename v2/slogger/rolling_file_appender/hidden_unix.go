@@ -6,6 +6,6 @@ import (
 	"os"
 )
 
-func createHidden(path string) (*os.File, error) {
-	return os.Create(path)
+func createHidden(path string, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
 }
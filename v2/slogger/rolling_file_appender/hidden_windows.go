@@ -8,7 +8,8 @@ import (
 	"unsafe"
 )
 
-func createHidden(name string) (*os.File, error) {
+// createHidden ignores perm as Windows does not have Unix permissions
+func createHidden(name string, perm os.FileMode) (*os.File, error) {
 	var sa syscall.SecurityAttributes
 	sa.Length = uint32(unsafe.Sizeof(sa))
 	sa.InheritHandle = 1
@@ -0,0 +1,78 @@
+package rolling_file_appender
+
+import "os"
+
+// RotationEvent describes a rotated log passed to the post-rotation
+// hook.
+type RotationEvent struct {
+	// Path is the path of the rotated log
+	Path string
+
+	// Reason is why the log was rotated.  It is RotationReasonNone
+	// for logs that were rotated before the appender was created.
+	Reason RotationReason
+
+	// Compressed is true if Path is the compressed log
+	Compressed bool
+}
+
+// addRotatedLog queues the post-rotation hook for a log that was just
+// rotated.  The lock should be held when calling this.
+func (self *RollingFileAppender) addRotatedLog(path string, reason RotationReason) {
+	if self.postRotateHook == nil {
+		return
+	}
+
+	self.hookLock.Lock()
+	defer self.hookLock.Unlock()
+
+	self.rotatedLogs = append(self.rotatedLogs, RotationEvent{path, reason, false})
+	if !self.compressRotatedLogs || self.maxUncompressedLogs < 0 {
+		return
+	}
+
+	// forget logs that were removed or compressed by something else,
+	// such as another process sharing the log with file locking
+	for rotatedPath := range self.rotationReasons {
+		if _, err := os.Lstat(rotatedPath); os.IsNotExist(err) {
+			delete(self.rotationReasons, rotatedPath)
+		}
+	}
+	self.rotationReasons[path] = reason
+}
+
+func (self *RollingFileAppender) runPendingPostRotateHooks() {
+	if self.postRotateHook == nil {
+		return
+	}
+
+	self.hookLock.Lock()
+	events := self.rotatedLogs
+	self.rotatedLogs = nil
+	self.hookLock.Unlock()
+
+	for _, event := range events {
+		self.postRotateHook(event)
+	}
+}
+
+// runPostRotateHook reports that the rotated log at path was
+// compressed to path + suffix.
+func (self *RollingFileAppender) runPostRotateHook(path string, suffix string) {
+	if self.postRotateHook == nil {
+		return
+	}
+
+	self.hookLock.Lock()
+	reason := self.rotationReasons[path]
+	delete(self.rotationReasons, path)
+	self.hookLock.Unlock()
+
+	self.postRotateHook(RotationEvent{path + suffix, reason, true})
+}
+
+func (self *RollingFileAppender) forgetRotationReason(path string) {
+	self.hookLock.Lock()
+	defer self.hookLock.Unlock()
+	delete(self.rotationReasons, path)
+}
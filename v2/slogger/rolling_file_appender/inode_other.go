@@ -0,0 +1,14 @@
+//go:build !(aix || darwin || dragonfly || freebsd || illumos || js || linux || netbsd || openbsd || solaris)
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!js,!linux,!netbsd,!openbsd,!solaris
+
+package rolling_file_appender
+
+import (
+	"os"
+)
+
+// inode returns 0 as os.FileInfo does not expose a file index on
+// Windows and other platforms without syscall.Stat_t
+func inode(fileInfo os.FileInfo) uint64 {
+	return 0
+}
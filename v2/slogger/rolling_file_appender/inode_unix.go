@@ -0,0 +1,16 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || js || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos js linux netbsd openbsd solaris
+
+package rolling_file_appender
+
+import (
+	"os"
+	"syscall"
+)
+
+func inode(fileInfo os.FileInfo) uint64 {
+	if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
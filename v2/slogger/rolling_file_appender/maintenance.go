@@ -0,0 +1,123 @@
+package rolling_file_appender
+
+// maintenanceQueueCapacity bounds the number of pending maintenance
+// requests.  Each pass rescans the log directory, so a single pending
+// request covers any number of rotations that happen before the
+// worker gets to it; further requests are dropped.
+const maintenanceQueueCapacity = 1
+
+// maintain consumes requests sent down the maintenance channel,
+// compressing and removing rotated logs as configured.  It runs in
+// its own goroutine so that rotate() does not hold the appender lock
+// while large files are being compressed.  A request may carry a
+// reply channel, which is closed once the pass has finished.  Once
+// maintenanceStop is closed, it performs any pass still queued and
+// exits.
+func (self *RollingFileAppender) maintain(requests <-chan chan struct{}) {
+	defer close(self.maintenanceDone)
+
+	for {
+		select {
+		case replyCh := <-requests:
+			self.performMaintenancePass(replyCh)
+		case <-self.maintenanceStop:
+			for {
+				select {
+				case replyCh := <-requests:
+					self.performMaintenancePass(replyCh)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (self *RollingFileAppender) performMaintenancePass(replyCh chan struct{}) {
+	self.performMaintenance()
+	if replyCh != nil {
+		close(replyCh)
+	}
+}
+
+func (self *RollingFileAppender) performMaintenance() {
+	// report rotated logs before they might be removed
+	self.runPendingPostRotateHooks()
+
+	// the rotated logs are listed again once the lock is held, so
+	// those another process removed or compressed meanwhile are not
+	// touched
+	err := self.withMaintenanceLock(func() {
+		// remove really old logs first so that we do not waste time
+		// compressing them
+		if err := self.removeMaxRotatedLogs(); err != nil {
+			self.handleError(err)
+		}
+		if err := self.cleanupForDiskSpace(); err != nil {
+			self.handleError(err)
+		}
+
+		if self.compressRotatedLogs {
+			if err := self.compressMaxUncompressedLogs(); err != nil {
+				self.handleError(err)
+			}
+		}
+	})
+	if err != nil {
+		self.handleError(err)
+	}
+}
+
+// requestMaintenance schedules a maintenance pass without blocking.
+func (self *RollingFileAppender) requestMaintenance() {
+	select {
+	case self.maintenanceCh <- nil:
+	default:
+		// a pass is already pending and will pick up our work
+	}
+}
+
+func (self *RollingFileAppender) startMaintenance() {
+	go self.maintain(self.maintenanceCh)
+}
+
+// stopMaintenance stops accepting maintenance requests and waits for
+// any pass in progress (or already queued) to finish.
+func (self *RollingFileAppender) stopMaintenance() {
+	self.lock.Lock()
+	if !self.maintenanceStopped {
+		self.maintenanceStopped = true
+		close(self.maintenanceStop)
+	}
+	self.lock.Unlock()
+
+	<-self.maintenanceDone
+}
+
+// waitForMaintenance blocks until every maintenance pass requested
+// before the call has finished.  It must not be called with the lock
+// held, as the worker may need the lock to report errors or run the
+// post-rotation hook, either of which may log to this appender.
+func (self *RollingFileAppender) waitForMaintenance() {
+	replyCh := make(chan struct{})
+
+	select {
+	case self.maintenanceCh <- replyCh:
+	case <-self.maintenanceStop:
+		// the worker performs what is queued before it exits
+		<-self.maintenanceDone
+		return
+	}
+
+	select {
+	case <-replyCh:
+	case <-self.maintenanceDone:
+		// queued after the worker's last pass
+	}
+}
+
+func (self *RollingFileAppender) handleError(err error) {
+	if self.errHandler != nil {
+		self.errHandler(err)
+	}
+}
@@ -0,0 +1,58 @@
+package rolling_file_appender
+
+import (
+	"time"
+)
+
+// periodicTask is a goroutine that calls a function with the lock
+// held every interval until stopped.
+type periodicTask struct {
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// startPeriodicTask starts calling f every interval.  Errors returned
+// by f are passed to the errHandler.  The task is stopped by Close().
+// This should only be called while building the appender.
+func (self *RollingFileAppender) startPeriodicTask(interval time.Duration, f func() error) {
+	task := &periodicTask{
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	self.periodicTasks = append(self.periodicTasks, task)
+
+	go func() {
+		defer close(task.doneCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-task.stopCh:
+				return
+			case <-ticker.C:
+				self.lock.Lock()
+				err := f()
+				self.lock.Unlock()
+				if err != nil {
+					self.handleError(err)
+				}
+			}
+		}
+	}()
+}
+
+// stopPeriodicTasks stops the tasks started by startPeriodicTask() and
+// waits for them to exit.
+func (self *RollingFileAppender) stopPeriodicTasks() {
+	self.lock.Lock()
+	tasks := self.periodicTasks
+	self.periodicTasks = nil
+	self.lock.Unlock()
+
+	for _, task := range tasks {
+		close(task.stopCh)
+		<-task.doneCh
+	}
+}
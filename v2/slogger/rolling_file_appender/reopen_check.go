@@ -0,0 +1,83 @@
+package rolling_file_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"os"
+	"time"
+)
+
+// movedExternally reports whether the file at absPath is no longer
+// the file we are writing to.  This is the case when something else
+// renamed or deleted it.  The lock should be held when calling this.
+func (self *RollingFileAppender) movedExternally() (bool, error) {
+	if self.file == nil {
+		return false, nil
+	}
+
+	openInfo, err := self.file.Stat()
+	if err != nil {
+		return false, &StatError{self.absPath, err}
+	}
+
+	pathInfo, err := os.Stat(self.absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, &StatError{self.absPath, err}
+	}
+
+	return !os.SameFile(openInfo, pathInfo), nil
+}
+
+// reopenIfMoved reopens absPath if the log file was moved or deleted
+// and logs a notice saying so at the top of the new file.  With file
+// locking, the log is taken to have been rotated by another process
+// sharing it, so it is switched to like rotate() does, keeping the
+// state that process wrote.  The lock should be held when calling
+// this.
+func (self *RollingFileAppender) reopenIfMoved() error {
+	if self.fileLocking {
+		// only take the file lock once the log looks moved, as this is
+		// checked before every append by default
+		if moved, err := self.movedExternally(); err != nil || !moved {
+			return err
+		}
+		return self.withFileLock(func() error {
+			moved, err := self.movedExternally()
+			if err != nil || !moved {
+				return err
+			}
+			return self.reopenRotatedByOther()
+		})
+	}
+
+	moved, err := self.movedExternally()
+	if err != nil || !moved {
+		return err
+	}
+
+	if err = self.reopen(); err != nil {
+		return err
+	}
+
+	notice := &slogger.Log{
+		Prefix:     "rolling_file_appender",
+		Level:      slogger.INFO,
+		Timestamp:  time.Now(),
+		MessageFmt: "Reopened %s after it was moved or deleted",
+		Args:       []interface{}{self.absPath},
+	}
+	n, err := self.appendSansSizeTracking(notice)
+	self.curFileSize += int64(n)
+	return err
+}
+
+func (self *RollingFileAppender) startReopenCheck() {
+	if self.reopenCheckInterval <= 0 {
+		return
+	}
+
+	self.startPeriodicTask(self.reopenCheckInterval, self.reopenIfMoved)
+}
@@ -0,0 +1,182 @@
+package rolling_file_appender
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy decides which rotated logs of a log file to keep
+// and which of those to compress.  It is applied by PlanRetention
+// without a RollingFileAppender, for example to clean up after a
+// process that is no longer running.  Zero values disable a limit.
+type RetentionPolicy struct {
+	// MaxRotatedLogs is the number of newest rotated logs to keep,
+	// like the maxRotatedLogs of a RollingFileAppender.
+	MaxRotatedLogs int
+
+	// MaxAge removes rotated logs that were rotated longer ago.
+	MaxAge time.Duration
+
+	// MaxTotalSize removes the oldest rotated logs until the ones
+	// left take up at most this many bytes.  Sizes are measured on
+	// disk, so compressed logs count with their compressed size.
+	MaxTotalSize int64
+
+	// Compressor, if not nil, compresses all but the newest
+	// MaxUncompressedLogs of the rotated logs that are kept.
+	Compressor          Compressor
+	MaxUncompressedLogs int
+}
+
+// RetentionPlan lists what applying a RetentionPolicy to a log file
+// would do.  Logs are listed oldest first.
+type RetentionPlan struct {
+	Remove   []string
+	Compress []string
+
+	compressor Compressor
+}
+
+// PlanRetention works out what applying policy to the rotated logs of
+// the log file at path would do at time now.  Nothing is changed until
+// Apply is called on the plan.
+func PlanRetention(path string, policy RetentionPolicy, now time.Time) (*RetentionPlan, error) {
+	if policy.Compressor != nil {
+		if err := checkCompressor(policy.Compressor); err != nil {
+			return nil, err
+		}
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rotationTimes, err := rotationTimeSliceForPath(absPath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(rotationTimes)
+
+	plan := &RetentionPlan{compressor: policy.Compressor}
+
+	// walk from the newest log back, keeping logs until a limit is hit
+	keep := 0
+	var totalSize int64
+	for i := len(rotationTimes) - 1; i >= 0; i-- {
+		rotationTime := rotationTimes[i]
+
+		info, err := os.Stat(rotationTime.Filename)
+		if err != nil {
+			return nil, &StatError{rotationTime.Filename, err}
+		}
+		totalSize += info.Size()
+
+		// rotated filenames hold the local time of rotation
+		rotatedAt := time.Date(
+			rotationTime.Time.Year(), rotationTime.Time.Month(), rotationTime.Time.Day(),
+			rotationTime.Time.Hour(), rotationTime.Time.Minute(), rotationTime.Time.Second(),
+			0, time.Local,
+		)
+
+		if (policy.MaxRotatedLogs > 0 && keep >= policy.MaxRotatedLogs) ||
+			(policy.MaxAge > 0 && now.Sub(rotatedAt) > policy.MaxAge) ||
+			(policy.MaxTotalSize > 0 && totalSize > policy.MaxTotalSize) {
+			for _, older := range rotationTimes[:i+1] {
+				plan.Remove = append(plan.Remove, older.Filename)
+			}
+			rotationTimes = rotationTimes[i+1:]
+			break
+		}
+		keep++
+	}
+
+	if policy.Compressor != nil {
+		uncompressed := 0
+		for i := len(rotationTimes) - 1; i >= 0; i-- {
+			filename := rotationTimes[i].Filename
+			if isCompressed(filename) {
+				continue
+			}
+			uncompressed++
+			if uncompressed > policy.MaxUncompressedLogs {
+				plan.Compress = append(plan.Compress, filename)
+			}
+		}
+		// oldest first, like Remove
+		for i, j := 0, len(plan.Compress)-1; i < j; i, j = i+1, j-1 {
+			plan.Compress[i], plan.Compress[j] = plan.Compress[j], plan.Compress[i]
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply removes and compresses the logs listed in the plan.  It goes
+// on after errors, returning all of them.  Compressed logs keep the
+// permissions of the originals.
+func (self *RetentionPlan) Apply() []error {
+	var errs []error
+
+	if len(self.Compress) > 0 {
+		// keep the compressed logs recognizable as rotated logs
+		RegisterCompressor(self.compressor)
+	}
+
+	for _, filename := range self.Remove {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, filename := range self.Compress {
+		info, err := os.Stat(filename)
+		if err != nil {
+			errs = append(errs, &StatError{filename, err})
+			continue
+		}
+		if err = compressLogFile(filename, self.compressor, fileOptions{mode: info.Mode().Perm()}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// LogFilesInDirectory returns the paths of the log files that have
+// rotated logs in dir, whether or not the log files themselves still
+// exist.
+func LogFilesInDirectory(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var logFiles []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		base, ok := rotatedLogBase(name)
+		if !ok {
+			continue
+		}
+		if _, err := extractRotationTimeFromFilename(name); err != nil {
+			continue
+		}
+
+		logFile := filepath.Join(dir, base)
+		if !seen[logFile] {
+			seen[logFile] = true
+			logFiles = append(logFiles, logFile)
+		}
+	}
+
+	sort.Strings(logFiles)
+	return logFiles, nil
+}
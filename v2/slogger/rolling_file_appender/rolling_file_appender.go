@@ -20,13 +20,13 @@ package rolling_file_appender
 import (
 	"github.com/mongodb/slogger/v2/slogger"
 
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -37,19 +37,76 @@ type RollingFileAppender struct {
 	maxDuration          time.Duration
 	maxRotatedLogs       int
 	compressRotatedLogs  bool
+	compressor           Compressor
 	maxUncompressedLogs  int
 	absPath              string
 	headerGenerator      func() []string
+	previousFileHeaders  func(string) []string
+	footerGenerator      func(FileStats) []string
 	stringWriterCallback func(*os.File) slogger.StringWriter
+	errHandler           func(error)
+	reopenCheckInterval  time.Duration
+	reopenCheckAppends   int
+	fileLocking          bool
+	preRotateHook        func(string, RotationReason)
+	postRotateHook       func(RotationEvent)
+	currentSymlink       string
+	fileOptions          fileOptions
+	diskSpaceGuard       *DiskSpaceGuard
+	writeBufferSize      int
+	flushInterval        time.Duration
+	durability           Durability
+
+	// chain, if not nil, tags every line written with a hash chain
+	chain *hashChain
 
 	lock sync.Mutex
 
+	// hookLock protects rotatedLogs, which holds the rotated logs
+	// that the post-rotation hook has not been run for yet, and
+	// rotationReasons, which maps the paths of uncompressed rotated
+	// logs to why they were rotated.  It is separate from lock as it
+	// is needed by the maintenance goroutine.
+	hookLock        sync.Mutex
+	rotatedLogs     []RotationEvent
+	rotationReasons map[string]RotationReason
+
+	// maintenanceCh carries requests to the background goroutine
+	// that compresses and removes rotated logs.  It is never closed;
+	// maintenanceStop is closed instead once the appender is closed,
+	// which the lock protects with maintenanceStopped.
+	// maintenanceDone is closed when the goroutine exits.
+	maintenanceCh      chan chan struct{}
+	maintenanceStop    chan struct{}
+	maintenanceStopped bool
+	maintenanceDone    chan struct{}
+
 	// These fields can change and the lock should be held when
 	// reading or writing to them after construction of the
 	// RollingFileAppender struct
 	file        *os.File
 	curFileSize int64
 
+	// buffer, if not nil, buffers writes to file
+	buffer *bufio.Writer
+
+	// stats describes what has been written to file
+	stats fileStats
+
+	// diskSpaceState is what the disk space guard, if any, is
+	// currently doing about low disk space
+	diskSpaceState     DiskSpaceState
+	lastDiskSpaceCheck time.Time
+
+	// appendsSinceReopenCheck counts appends since the open file was
+	// last compared against absPath
+	appendsSinceReopenCheck int
+
+	// periodicTasks are the goroutines doing periodic work, such as
+	// checking whether the log file was moved.  It is set to nil
+	// once they are stopped.
+	periodicTasks []*periodicTask
+
 	// state holds "state" that is written to disk in a hidden state
 	// file.  Not all "state" needs to go in here.  For example, the
 	// current file size can be determined by a stat system call on
@@ -65,9 +122,26 @@ type rollingFileAppenderBuilder struct {
 	maxRotatedLogs       int
 	rotateIfExists       bool
 	compressRotatedLogs  bool
+	compressor           Compressor
 	maxUncompressedLogs  int
 	headerGenerator      func() []string
+	previousFileHeaders  func(string) []string
+	footerGenerator      func(FileStats) []string
 	stringWriterCallback func(*os.File) slogger.StringWriter
+	errHandler           func(error)
+	reopenCheckInterval  time.Duration
+	reopenCheckAppends   int
+	fileLocking          bool
+	preRotateHook        func(string, RotationReason)
+	postRotateHook       func(RotationEvent)
+	currentSymlink       string
+	fileOptions          fileOptions
+	diskSpaceGuard       *DiskSpaceGuard
+	writeBufferSize      int
+	flushInterval        time.Duration
+	durability           Durability
+	hashChain            bool
+	encryptionKeys       *EncryptionKeys
 }
 
 // NewBuilder returns a new rollingFileAppenderBuilder. You can directly
@@ -105,9 +179,10 @@ type rollingFileAppenderBuilder struct {
 // to.  If a log file with the same filename does not exist, then a
 // new log file is created regardless of the value of rotateIfExists.
 //
-// As RotatingFileAppender might be wrapped by an AsyncAppender, an
-// errHandler can be provided that will be called when an error
-// occurs.  It can set to nil if you do not want to provide one.
+// Rotated logs are compressed and removed in the background.  As
+// errors from that work cannot be returned to the caller, an
+// errHandler can be provided with WithErrHandler() that will be
+// called when one occurs.
 //
 // The return value headerGenerator, if not nil, is logged at the
 // beginning of every log file.
@@ -124,18 +199,216 @@ func NewBuilder(filename string, maxFileSize int64, maxDuration time.Duration, m
 		maxRotatedLogs:       maxRotatedLogs,
 		rotateIfExists:       rotateIfExists,
 		compressRotatedLogs:  false,
+		compressor:           nil,
 		maxUncompressedLogs:  0,
 		headerGenerator:      headerGenerator,
 		stringWriterCallback: nil,
 	}
 }
 
+// WithLogCompression enables compression of rotated logs.  All but
+// the maxUncompressedLogs most recent rotated logs are compressed.
+// Set maxUncompressedLogs to a negative number to not compress any.
+// Logs are compressed with gzip at the default level unless another
+// Compressor is set with WithCompressor().
 func (b *rollingFileAppenderBuilder) WithLogCompression(maxUncompressedLogs int) *rollingFileAppenderBuilder {
 	b.compressRotatedLogs = true
 	b.maxUncompressedLogs = maxUncompressedLogs
 	return b
 }
 
+// WithCompressor sets the Compressor used for rotated logs when
+// compression is enabled with WithLogCompression().  Build()
+// registers the Compressor as RegisterCompressor() does, unless one is
+// registered for its suffix already.
+func (b *rollingFileAppenderBuilder) WithCompressor(compressor Compressor) *rollingFileAppenderBuilder {
+	b.compressor = compressor
+	return b
+}
+
+// WithEncryption makes compressed rotated logs encrypted as well, using
+// an EncryptingCompressor around the Compressor.  Compression must be
+// enabled with WithLogCompression(); the maxUncompressedLogs most
+// recent rotated logs are left unencrypted as well.  Build() calls
+// keys.Current once to check that a key can be had.
+func (b *rollingFileAppenderBuilder) WithEncryption(keys EncryptionKeys) *rollingFileAppenderBuilder {
+	b.encryptionKeys = &keys
+	return b
+}
+
+// WithErrHandler sets a function that is called with errors that are
+// not returned to the caller, such as those that occur while
+// compressing or removing rotated logs in the background or a
+// corrupt state file that was replaced.
+func (b *rollingFileAppenderBuilder) WithErrHandler(errHandler func(error)) *rollingFileAppenderBuilder {
+	b.errHandler = errHandler
+	return b
+}
+
+// WithReopenCheckInterval makes the appender check every interval
+// whether the log file was moved or deleted by something else (for
+// example logrotate) and if so, reopen it.  This removes the need to
+// call Reopen() after external rotation.
+func (b *rollingFileAppenderBuilder) WithReopenCheckInterval(interval time.Duration) *rollingFileAppenderBuilder {
+	b.reopenCheckInterval = interval
+	return b
+}
+
+// WithReopenCheckAppends is like WithReopenCheckInterval but checks
+// before every nth append instead of on a timer.
+func (b *rollingFileAppenderBuilder) WithReopenCheckAppends(n int) *rollingFileAppenderBuilder {
+	b.reopenCheckAppends = n
+	return b
+}
+
+// WithFileLocking makes rotation safe when several processes log to
+// the same file.  An advisory lock is held on a hidden lock file next
+// to the log while rotating and while writing the state file.  A
+// process that finds the log already rotated by another process
+// reopens the new log rather than rotating it again.  Removal and
+// compression of rotated logs take a second hidden lock, so that
+// processes do not work on the same rotated logs at once.  Locking is
+// only supported on platforms with flock(2) and is a no-op elsewhere.
+//
+// As another process may compress or remove a log it rotated, a
+// process has to stop writing to the rotated log quickly.  So unless
+// WithReopenCheckAppends sets another count, the log is checked for
+// having been rotated by another process before every append.
+func (b *rollingFileAppenderBuilder) WithFileLocking() *rollingFileAppenderBuilder {
+	b.fileLocking = true
+	return b
+}
+
+// WithPreRotateHook sets a function that is called with the path of
+// the current log and the reason for rotating it just before it is
+// closed and renamed.  It is called with the appender locked, so it
+// must not log to this appender.
+func (b *rollingFileAppenderBuilder) WithPreRotateHook(hook func(path string, reason RotationReason)) *rollingFileAppenderBuilder {
+	b.preRotateHook = hook
+	return b
+}
+
+// WithPostRotateHook sets a function that is called once a rotated
+// log is complete: first after it has been renamed and then, if it is
+// compressed, again after compression.  The hook is called from the
+// goroutine that compresses and removes rotated logs, before any logs
+// are removed, so it may take its time (for example to upload the
+// log).  Close() waits for pending hooks.  Logs moved by external
+// rotation followed by Reopen() are not reported.
+func (b *rollingFileAppenderBuilder) WithPostRotateHook(hook func(event RotationEvent)) *rollingFileAppenderBuilder {
+	b.postRotateHook = hook
+	return b
+}
+
+// WithPreviousFileHeaderGenerator sets a header generator that is
+// passed the path the previous log was rotated to, so the header can
+// refer to it.  The path is empty if there is no previous log or it
+// is not known (for example after Reopen()).  It is used instead of
+// the headerGenerator passed to NewBuilder().
+func (b *rollingFileAppenderBuilder) WithPreviousFileHeaderGenerator(headerGenerator func(previousFilename string) []string) *rollingFileAppenderBuilder {
+	b.previousFileHeaders = headerGenerator
+	return b
+}
+
+// WithFooterGenerator sets a function whose return value is logged
+// at the end of every log file, when it is rotated or the appender is
+// closed.  It is passed statistics about the file.
+func (b *rollingFileAppenderBuilder) WithFooterGenerator(footerGenerator func(stats FileStats) []string) *rollingFileAppenderBuilder {
+	b.footerGenerator = footerGenerator
+	return b
+}
+
+// WithCurrentSymlink makes the appender maintain a symbolic link at
+// linkPath that points at the log file, for example to give the log a
+// well-known name in another directory for tools that tail it.  As
+// the appender always writes to the same path, the link's target does
+// not change, but the link is put back (atomically, by renaming a new
+// link over it) whenever a new log file is started, so that it
+// survives being removed or replaced.  If linkPath is empty, a link
+// named after the log file with a ".current" suffix is created next
+// to it, which keeps appenders that share a directory apart.
+// Failures to update the link are passed to the errHandler rather
+// than failing the write.
+func (b *rollingFileAppenderBuilder) WithCurrentSymlink(linkPath string) *rollingFileAppenderBuilder {
+	if linkPath == "" {
+		linkPath = b.filename + ".current"
+	}
+	b.currentSymlink = linkPath
+	return b
+}
+
+// WithFileMode sets the permissions of the files the appender
+// creates: the log files (active, rotated and compressed) as well as
+// its hidden state and lock files.  Unlike the default of 0666 less
+// the umask, mode is applied exactly.
+func (b *rollingFileAppenderBuilder) WithFileMode(mode os.FileMode) *rollingFileAppenderBuilder {
+	b.fileOptions.mode = mode
+	return b
+}
+
+// WithOwner makes the appender chown the files it creates (and any
+// directories created because of WithDirectoryCreation()) to uid and
+// gid.  Pass -1 for either to leave it unchanged.  This usually
+// requires privileges and is not supported on Windows.
+func (b *rollingFileAppenderBuilder) WithOwner(uid, gid int) *rollingFileAppenderBuilder {
+	b.fileOptions.chown = true
+	b.fileOptions.uid = uid
+	b.fileOptions.gid = gid
+	return b
+}
+
+// WithDirectoryCreation makes the appender create the log file's
+// parent directories with the given mode if they do not exist.
+func (b *rollingFileAppenderBuilder) WithDirectoryCreation(dirMode os.FileMode) *rollingFileAppenderBuilder {
+	b.fileOptions.createDirs = true
+	b.fileOptions.dirMode = dirMode
+	return b
+}
+
+// WithDiskSpaceGuard makes the appender check the free space on the
+// filesystem holding its logs and, as it runs low, remove old rotated
+// logs early, drop less important logs and finally fall back to
+// another appender rather than failing every write.  See
+// DiskSpaceGuard.  Free space can only be checked on Linux, macOS,
+// FreeBSD, DragonFly BSD and OpenBSD.  Elsewhere every check passes a
+// StatError to the errHandler and no measures are taken.
+func (b *rollingFileAppenderBuilder) WithDiskSpaceGuard(guard DiskSpaceGuard) *rollingFileAppenderBuilder {
+	b.diskSpaceGuard = &guard
+	return b
+}
+
+// WithWriteBuffer makes the appender buffer up to size bytes of logs
+// in memory rather than writing each log to the file right away.  The
+// buffer is written out when it is full, every flushInterval (if
+// positive), and before the file is synced, rotated or closed.  The
+// file size used for size-based rotation includes buffered logs.
+func (b *rollingFileAppenderBuilder) WithWriteBuffer(size int, flushInterval time.Duration) *rollingFileAppenderBuilder {
+	b.writeBufferSize = size
+	b.flushInterval = flushInterval
+	return b
+}
+
+// WithDurability sets when the log file is synced to disk, in addition
+// to on Flush() and Close().  DurabilityFlushOnInterval uses the flush
+// interval given to WithWriteBuffer().
+func (b *rollingFileAppenderBuilder) WithDurability(durability Durability) *rollingFileAppenderBuilder {
+	b.durability = durability
+	return b
+}
+
+// WithHashChain makes the log tamper-evident by tagging every line with
+// a SHA-256 hash chained over all lines before it, including those in
+// rotated logs.  Use VerifyChain to check a log set.  The chain is not
+// keyed, so it detects accidental or careless edits, not an attacker
+// who can rewrite the logs and the state file.  The log is flushed and
+// synced before the head of the chain is recorded in the state file at
+// rotation.  Hash chaining needs a single writer, so it cannot be
+// combined with WithFileLocking().
+func (b *rollingFileAppenderBuilder) WithHashChain() *rollingFileAppenderBuilder {
+	b.hashChain = true
+	return b
+}
+
 func (b *rollingFileAppenderBuilder) WithStringWriter(stringWriterCallback func(*os.File) slogger.StringWriter) *rollingFileAppenderBuilder {
 	b.stringWriterCallback = stringWriterCallback
 	return b
@@ -152,61 +425,132 @@ func (b *rollingFileAppenderBuilder) Build() (*RollingFileAppender, error) {
 			return f
 		}
 	}
+	if b.compressor == nil {
+		b.compressor = GzipCompressor(gzip.DefaultCompression)
+	}
+	compressor := b.compressor
+	if b.encryptionKeys != nil {
+		if !b.compressRotatedLogs {
+			return nil, fmt.Errorf("rolling_file_appender: Encryption requires log compression")
+		}
+		compressor = EncryptingCompressor(compressor, *b.encryptionKeys)
+	}
+	if err := checkCompressor(compressor); err != nil {
+		return nil, err
+	}
+	if b.durability == DurabilityFlushOnInterval && b.flushInterval <= 0 {
+		return nil, fmt.Errorf("rolling_file_appender: DurabilityFlushOnInterval requires a positive flush interval")
+	}
+	if b.hashChain && b.fileLocking {
+		return nil, fmt.Errorf("rolling_file_appender: Hash chaining cannot be combined with file locking")
+	}
+	registerBuiltCompressor(compressor)
+
+	reopenCheckAppends := b.reopenCheckAppends
+	if b.fileLocking && reopenCheckAppends <= 0 {
+		reopenCheckAppends = 1
+	}
 
 	absPath, err := filepath.Abs(b.filename)
 	if err != nil {
 		return nil, err
 	}
 
+	var currentSymlink string
+	if b.currentSymlink != "" {
+		if currentSymlink, err = filepath.Abs(b.currentSymlink); err != nil {
+			return nil, err
+		}
+	}
+
 	appender := &RollingFileAppender{
 		maxFileSize:          b.maxFileSize,
 		maxDuration:          b.maxDuration,
 		maxRotatedLogs:       b.maxRotatedLogs,
 		compressRotatedLogs:  b.compressRotatedLogs,
+		compressor:           compressor,
 		maxUncompressedLogs:  b.maxUncompressedLogs,
 		absPath:              absPath,
 		headerGenerator:      b.headerGenerator,
+		previousFileHeaders:  b.previousFileHeaders,
+		footerGenerator:      b.footerGenerator,
 		stringWriterCallback: b.stringWriterCallback,
+		errHandler:           b.errHandler,
+		reopenCheckInterval:  b.reopenCheckInterval,
+		reopenCheckAppends:   reopenCheckAppends,
+		fileLocking:          b.fileLocking,
+		preRotateHook:        b.preRotateHook,
+		postRotateHook:       b.postRotateHook,
+		currentSymlink:       currentSymlink,
+		fileOptions:          b.fileOptions,
+		diskSpaceGuard:       b.diskSpaceGuard,
+		writeBufferSize:      b.writeBufferSize,
+		flushInterval:        b.flushInterval,
+		durability:           b.durability,
+		rotationReasons:      make(map[string]RotationReason),
+		maintenanceCh:        make(chan chan struct{}, maintenanceQueueCapacity),
+		maintenanceStop:      make(chan struct{}),
+		maintenanceDone:      make(chan struct{}),
+	}
+
+	if b.hashChain {
+		appender.chain = &hashChain{head: lastChainHead(absPath)}
+	}
+
+	// pick up any rotated logs left uncompressed by a previous run
+	if appender.compressRotatedLogs {
+		appender.requestMaintenance()
+	}
+
+	if err = b.fileOptions.makeParentDirs(absPath); err != nil {
+		return nil, err
 	}
 
 	fileInfo, err := os.Stat(absPath)
 	if err == nil && b.rotateIfExists { // err == nil means file exists
-		return appender, appender.rotate()
+		// carry the rotation history over into the state that the
+		// startup rotation stamps
+		if err = appender.loadStateForStartupRotation(); err != nil {
+			return nil, err
+		}
+		err = appender.rotate(RotationReasonStartup)
 	} else {
 		// we're either creating a new log file or appending to the current one
-		appender.file, err = os.OpenFile(
+		var file *os.File
+		file, err = appender.fileOptions.openFile(
 			absPath,
 			os.O_WRONLY|os.O_APPEND|os.O_CREATE,
-			0666,
 		)
 		if err != nil {
 			return nil, err
 		}
+		appender.setFile(file)
 
 		if fileInfo != nil {
 			appender.curFileSize = fileInfo.Size()
 		}
+		if appender.chain != nil {
+			if err = appender.endTornLine(); err != nil {
+				appender.file.Close()
+				return nil, err
+			}
+		}
 
-		stateExistsVar, err := stateExists(appender.statePath())
-		if err != nil {
+		if err = appender.withFileLock(appender.loadOrStampState); err != nil {
 			appender.file.Close()
 			return nil, err
 		}
 
-		if stateExistsVar {
-			if err = appender.loadState(); err != nil {
-				appender.file.Close()
-				return nil, err
-			}
-		} else {
-			if err = appender.stampStartTime(); err != nil {
-				appender.file.Close()
-				return nil, err
-			}
-		}
+		err = appender.logHeader("")
+		appender.updateCurrentSymlink()
+	}
 
-		return appender, appender.logHeader()
+	appender.startMaintenance()
+	appender.startReopenCheck()
+	if appender.flushInterval > 0 {
+		appender.startPeriodicTask(appender.flushInterval, appender.flushOnInterval)
 	}
+	return appender, err
 }
 
 // New creates a new RollingFileAppender.
@@ -224,31 +568,62 @@ func (self *RollingFileAppender) Append(log *slogger.Log) error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
-	n, err := self.appendSansSizeTracking(log)
-	self.curFileSize += int64(n)
+	if self.reopenCheckAppends > 0 {
+		self.appendsSinceReopenCheck++
+		if self.appendsSinceReopenCheck >= self.reopenCheckAppends {
+			self.appendsSinceReopenCheck = 0
+			if err := self.reopenIfMoved(); err != nil {
+				return err
+			}
+		}
+	}
 
-	if err != nil {
+	self.checkDiskSpace()
+	if err := self.appendGuarded(log); err != nil {
 		return err
 	}
 
-	if (self.maxFileSize > 0 && self.curFileSize > self.maxFileSize) ||
-		(self.maxDuration > 0 &&
-			self.state != nil &&
-			time.Since(self.state.LogStartTime) > self.maxDuration) {
-		return self.rotate()
+	if self.maxFileSize > 0 && self.curFileSize > self.maxFileSize {
+		return self.rotate(RotationReasonSize)
+	}
+
+	if self.maxDuration > 0 &&
+		self.state != nil &&
+		time.Since(self.state.LogStartTime) > self.maxDuration {
+		return self.rotate(RotationReasonTime)
 	}
 
 	return nil
 }
 
+// Close closes the current log file.  It waits for any compression or
+// removal of rotated logs that is in progress to finish.
 func (self *RollingFileAppender) Close() error {
+	self.stopPeriodicTasks()
+	err := self.closeFile()
+	self.stopMaintenance()
+	return err
+}
+
+func (self *RollingFileAppender) closeFile() error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
-	if err := self.file.Sync(); err != nil {
+	if err := self.logFooter(RotationReasonNone); err != nil {
+		return err
+	}
+
+	if err := self.flushAndSync(); err != nil {
 		return err
 	}
 
+	if self.chain != nil && self.state != nil {
+		self.state.ChainHead = self.chainHead()
+		if err := self.state.write(self.statePath(), self.fileOptions); err != nil {
+			return err
+		}
+	}
+
 	if err := self.file.Close(); err != nil {
 		return &CloseError{self.absPath, err}
 	}
@@ -260,18 +635,20 @@ func (self *RollingFileAppender) Flush() error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
-	if err := self.file.Sync(); err != nil {
-		return &SyncError{self.absPath, err}
+	if self.diskSpaceGuard != nil && self.diskSpaceGuard.Fallback != nil {
+		if err := self.diskSpaceGuard.Fallback.Flush(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return self.flushAndSync()
 }
 
 func (self *RollingFileAppender) Rotate() error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
-	return self.rotate()
+	return self.rotate(RotationReasonManual)
 }
 
 // Useful for manual log rotation.  For example, logrotated may rename
@@ -282,10 +659,22 @@ func (self *RollingFileAppender) Reopen() error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
+	return self.reopen()
+}
+
+func (self *RollingFileAppender) reopen() error {
+	return self.withFileLock(self.reopenFile)
+}
+
+func (self *RollingFileAppender) reopenFile() error {
 	// close current log if we have one open
 	if self.file != nil {
-		if err := self.file.Sync(); err != nil {
-			return &SyncError{self.absPath, err}
+		if err := self.logFooter(RotationReasonReopen); err != nil {
+			return err
+		}
+
+		if err := self.flushAndSync(); err != nil {
+			return err
 		}
 
 		if err := self.file.Close(); err != nil {
@@ -300,21 +689,27 @@ func (self *RollingFileAppender) Reopen() error {
 		self.curFileSize = 0
 	}
 
-	file, err := os.OpenFile(self.absPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666) // umask applies to perms
+	if err = self.fileOptions.makeParentDirs(self.absPath); err != nil {
+		self.setFile(nil)
+		return &OpenError{self.absPath, err}
+	}
+
+	file, err := self.fileOptions.openFile(self.absPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE)
 	if err != nil {
-		self.file = nil
+		self.setFile(nil)
 		return &OpenError{self.absPath, err}
 	}
-	self.file = file
-	self.logHeader()
+	self.setFile(file)
+	self.logHeader("")
+	self.updateCurrentSymlink()
 
 	// stamp start time
-	if err = self.stampStartTime(); err != nil {
+	if err = self.stampStartTime(RotationReasonReopen); err != nil {
 		return err
 	}
 
 	// remove really old logs
-	self.removeMaxRotatedLogs()
+	self.requestMaintenance()
 
 	return nil
 }
@@ -344,7 +739,15 @@ func (self *RollingFileAppender) appendSansSizeTracking(log *slogger.Log) (bytes
 	}
 	f := slogger.GetFormatLogFunc()
 	msg := f(log)
-	bytesWritten, err = self.stringWriterCallback(self.file).WriteString(msg)
+	if self.chain != nil {
+		msg = self.chain.tag(msg)
+	}
+	bytesWritten, err = self.writer().WriteString(msg)
+	if bytesWritten < len(msg) {
+		self.stats.record(msg[:bytesWritten], log.Timestamp)
+	} else {
+		self.stats.record(msg, log.Timestamp)
+	}
 
 	if err != nil {
 		err = &WriteError{self.absPath, err}
@@ -353,12 +756,42 @@ func (self *RollingFileAppender) appendSansSizeTracking(log *slogger.Log) (bytes
 	return
 }
 
-func (self *RollingFileAppender) logHeader() error {
-	header := self.headerGenerator()
-	for _, line := range header {
+func (self *RollingFileAppender) logHeader(previousFilename string) error {
+	if err := self.logChainAnchor(); err != nil {
+		return err
+	}
+
+	var header []string
+	if self.previousFileHeaders != nil {
+		header = self.previousFileHeaders(previousFilename)
+	} else {
+		header = self.headerGenerator()
+	}
+	return self.logSpecialLines("header", header)
+}
+
+// logFooter logs the footer, if any, at the end of the current log
+// file, which is being closed because of reason.
+func (self *RollingFileAppender) logFooter(reason RotationReason) error {
+	if self.file == nil {
+		return nil
+	}
+
+	if self.footerGenerator != nil {
+		footer := self.footerGenerator(self.stats.snapshot(self.absPath, reason))
+		if err := self.logSpecialLines("footer", footer); err != nil {
+			return err
+		}
+	}
+
+	return self.logChainHead()
+}
+
+func (self *RollingFileAppender) logSpecialLines(prefix string, lines []string) error {
+	for _, line := range lines {
 
 		log := &slogger.Log{
-			Prefix:     "header",
+			Prefix:     prefix,
 			Level:      slogger.INFO,
 			Filename:   "",
 			Line:       0,
@@ -367,9 +800,9 @@ func (self *RollingFileAppender) logHeader() error {
 			Args:       []interface{}{},
 		}
 
-		// do not count header as part of size towards rotation in
-		// order to prevent infinite rotation when max size is smaller
-		// than header
+		// do not count header (or footer) as part of size towards
+		// rotation in order to prevent infinite rotation when max
+		// size is smaller than header
 		_, err := self.appendSansSizeTracking(log)
 		if err != nil {
 			return err
@@ -404,13 +837,14 @@ func (self *RollingFileAppender) removeMaxRotatedLogs() error {
 		if err = os.Remove(rotationTime.Filename); err != nil {
 			return &MinorRotationError{err}
 		}
+		self.forgetRotationReason(rotationTime.Filename)
 	}
 	return nil
 }
 
 const MAX_ROTATE_SERIAL_NUM = 1000000000
 
-func (self *RollingFileAppender) renameLogFile(oldFilename string) error {
+func (self *RollingFileAppender) renameLogFile(oldFilename string) (string, error) {
 	now := time.Now()
 
 	var newFilename string
@@ -418,7 +852,7 @@ func (self *RollingFileAppender) renameLogFile(oldFilename string) error {
 
 	for serial := 0; err == nil; serial++ { // err == nil means file exists
 		if serial > MAX_ROTATE_SERIAL_NUM {
-			return &RenameError{
+			return "", &RenameError{
 				oldFilename,
 				newFilename,
 				fmt.Errorf("Reached max serial number: %d", MAX_ROTATE_SERIAL_NUM),
@@ -426,14 +860,22 @@ func (self *RollingFileAppender) renameLogFile(oldFilename string) error {
 		}
 		newFilename = rotatedFilename(self.absPath, now, serial)
 		_, err = os.Stat(newFilename)
+		// a compressed log would be clobbered when the new
+		// rotated log gets compressed
+		for _, suffix := range compressedSuffixes() {
+			if err == nil {
+				break
+			}
+			_, err = os.Stat(newFilename + suffix)
+		}
 	}
 
 	err = os.Rename(oldFilename, newFilename)
 
 	if err != nil {
-		return &RenameError{oldFilename, newFilename, err}
+		return "", &RenameError{oldFilename, newFilename, err}
 	}
-	return nil
+	return newFilename, nil
 }
 
 func (self *RollingFileAppender) compressMaxUncompressedLogs() error {
@@ -449,7 +891,7 @@ func (self *RollingFileAppender) compressMaxUncompressedLogs() error {
 
 	uncompressedRotationTimes := make(RotationTimeSlice, 0, len(rotationTimes))
 	for _, v := range rotationTimes {
-		if !strings.HasSuffix(v.Filename, ".gz") {
+		if !isCompressed(v.Filename) {
 			uncompressedRotationTimes = append(uncompressedRotationTimes, v)
 		}
 	}
@@ -464,11 +906,18 @@ func (self *RollingFileAppender) compressMaxUncompressedLogs() error {
 		if err = self.compressLogFile(rotationTime.Filename); err != nil {
 			return &MinorRotationError{err}
 		}
+		self.runPostRotateHook(rotationTime.Filename, self.compressor.Suffix())
 	}
 	return nil
 }
 
 func (self *RollingFileAppender) compressLogFile(logpath string) error {
+	return compressLogFile(logpath, self.compressor, self.fileOptions)
+}
+
+// compressLogFile replaces logpath with a compressed copy that keeps
+// its modification time.
+func compressLogFile(logpath string, compressor Compressor, fileOptions fileOptions) error {
 	f, err := os.Open(logpath)
 	if err != nil {
 		return fmt.Errorf("error trying to open %v, %v", logpath, err)
@@ -479,22 +928,27 @@ func (self *RollingFileAppender) compressLogFile(logpath string) error {
 	if err != nil {
 		return fmt.Errorf("error trying to stat %v, %v", logpath, err)
 	}
-	compressedF, err := os.Create(logpath + ".gz")
-	defer compressedF.Close()
+	compressedF, err := fileOptions.openFile(logpath+compressor.Suffix(), os.O_RDWR|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
-		return fmt.Errorf("error trying to create %v, %v", compressedF, err)
+		return fmt.Errorf("error trying to create %v, %v", logpath+compressor.Suffix(), err)
 	}
+	defer compressedF.Close()
 
-	gzipWriter := gzip.NewWriter(compressedF)
-	defer gzipWriter.Close()
-	gzipWriter.ModTime = info.ModTime()
+	compressWriter, err := compressor.NewWriter(compressedF)
+	if err != nil {
+		return fmt.Errorf("error creating compressor for %v, %v", logpath, err)
+	}
+	defer compressWriter.Close()
+	if gzipWriter, ok := compressWriter.(*gzip.Writer); ok {
+		gzipWriter.ModTime = info.ModTime()
+	}
 
-	if _, err := io.Copy(gzipWriter, f); err != nil {
+	if _, err := io.Copy(compressWriter, f); err != nil {
 		return fmt.Errorf("error compressing %v, %v", logpath, err)
 	}
 
-	if err := gzipWriter.Close(); err != nil {
-		return fmt.Errorf("error closing gzipWriter, %v", err)
+	if err := compressWriter.Close(); err != nil {
+		return fmt.Errorf("error closing compressor, %v", err)
 	}
 
 	if err := compressedF.Close(); err != nil {
@@ -516,9 +970,67 @@ func (self *RollingFileAppender) compressLogFile(logpath string) error {
 	return nil
 }
 
-func (self *RollingFileAppender) rotate() error {
+func (self *RollingFileAppender) rotate(reason RotationReason) error {
+	return self.withFileLock(func() error {
+		if self.fileLocking {
+			moved, err := self.movedExternally()
+			if err != nil {
+				return err
+			}
+			if moved {
+				// another process sharing the log rotated it
+				// while we were waiting for the lock
+				return self.reopenRotatedByOther()
+			}
+		}
+
+		return self.rotateFile(reason)
+	})
+}
+
+// reopenRotatedByOther switches to the log that another process
+// created when rotating.  That process already logged the header and
+// wrote the state file, so we only need to read it in.
+func (self *RollingFileAppender) reopenRotatedByOther() error {
+	if err := self.flushBuffer(); err != nil {
+		return err
+	}
+
+	if err := self.file.Close(); err != nil {
+		return &CloseError{self.absPath, err}
+	}
+
+	file, err := self.fileOptions.openFile(self.absPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE)
+	if err != nil {
+		self.setFile(nil)
+		return &OpenError{self.absPath, err}
+	}
+	self.setFile(file)
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return &StatError{self.absPath, err}
+	}
+	self.curFileSize = fileInfo.Size()
+
+	return self.loadOrStampState()
+}
+
+func (self *RollingFileAppender) rotateFile(reason RotationReason) error {
+	if self.preRotateHook != nil {
+		self.preRotateHook(self.absPath, reason)
+	}
+
 	// close current log if we have one open
 	if self.file != nil {
+		if err := self.logFooter(reason); err != nil {
+			return err
+		}
+
+		if err := self.flushBuffer(); err != nil {
+			return err
+		}
+
 		if err := self.file.Close(); err != nil {
 			return &CloseError{self.absPath, err}
 		}
@@ -526,57 +1038,99 @@ func (self *RollingFileAppender) rotate() error {
 	self.curFileSize = 0
 
 	// rename old log
-	err := self.renameLogFile(self.absPath)
+	rotatedPath, err := self.renameLogFile(self.absPath)
 	if err != nil {
 		return err
 	}
+	self.addRotatedLog(rotatedPath, reason)
 
 	// create new log
-	file, err := os.Create(self.absPath)
+	file, err := self.fileOptions.openFile(self.absPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
-		self.file = nil
+		self.setFile(nil)
 		return &OpenError{self.absPath, err}
 	}
-	self.file = file
-	self.logHeader()
+	self.setFile(file)
+	self.logHeader(rotatedPath)
+	self.updateCurrentSymlink()
 
 	// stamp start time
-	if err = self.stampStartTime(); err != nil {
+	if err = self.stampStartTime(reason); err != nil {
 		return err
 	}
 
-	if self.compressRotatedLogs {
-		if err = self.compressMaxUncompressedLogs(); err != nil {
-			return err
-		}
-	}
-	// remove really old logs
-	self.removeMaxRotatedLogs()
+	// compress and remove old logs in the background
+	self.requestMaintenance()
 
 	return nil
 }
 
 func (self *RollingFileAppender) rotationTimeSlice() (RotationTimeSlice, error) {
-	candidateFilenames, err := filepath.Glob(self.absPath + ".*")
+	return rotationTimeSliceForPath(self.absPath)
+}
 
+func (self *RollingFileAppender) loadState() error {
+	state, err := readState(self.statePath())
+	if IsDecodeError(err) {
+		state, err = self.recoverState(err)
+	}
 	if err != nil {
+		return err
+	}
+
+	self.state = state
+	return nil
+}
+
+// recoverState replaces a state file that could not be decoded,
+// using the log file's modification time as its start time.  The
+// decode error is passed to the errHandler and logged as a warning
+// rather than preventing the appender from starting.
+func (self *RollingFileAppender) recoverState(decodeErr error) (*state, error) {
+	startTime := time.Now()
+	if fileInfo, err := os.Stat(self.absPath); err == nil {
+		startTime = fileInfo.ModTime()
+	}
+
+	state := newState(startTime)
+	state.Inode = self.fileInode()
+	if err := state.write(self.statePath(), self.fileOptions); err != nil {
 		return nil, err
 	}
 
-	rotationTimes := make(RotationTimeSlice, 0, len(candidateFilenames))
+	self.handleError(decodeErr)
 
-	for _, candidateFilename := range candidateFilenames {
-		rotationTime, err := extractRotationTimeFromFilename(candidateFilename)
-		if err == nil {
-			rotationTimes = append(rotationTimes, rotationTime)
-		}
+	warning := &slogger.Log{
+		Prefix:     "rolling_file_appender",
+		Level:      slogger.WARN,
+		Timestamp:  time.Now(),
+		MessageFmt: "Replaced corrupt state file (%v). Using %v as the log start time",
+		Args:       []interface{}{decodeErr, startTime},
+	}
+	n, err := self.appendSansSizeTracking(warning)
+	self.curFileSize += int64(n)
+	if err != nil {
+		return nil, err
 	}
 
-	return rotationTimes, nil
+	return state, nil
 }
 
-func (self *RollingFileAppender) loadState() error {
+// loadStateForStartupRotation reads in the state file, if there is
+// one, ahead of a rotation at startup.  A state file that cannot be
+// decoded is reported to the errHandler and otherwise ignored, as the
+// rotation replaces it.
+func (self *RollingFileAppender) loadStateForStartupRotation() error {
+	stateExistsVar, err := stateExists(self.statePath())
+	if err != nil || !stateExistsVar {
+		return err
+	}
+
 	state, err := readState(self.statePath())
+	if IsDecodeError(err) {
+		self.handleError(err)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -585,11 +1139,121 @@ func (self *RollingFileAppender) loadState() error {
 	return nil
 }
 
-func (self *RollingFileAppender) stampStartTime() error {
-	state := newState(time.Now())
-	if err := state.write(self.statePath()); err != nil {
+// loadOrStampState reads in the state file if there is one and
+// otherwise creates it.
+func (self *RollingFileAppender) loadOrStampState() error {
+	stateExistsVar, err := stateExists(self.statePath())
+	if err != nil {
+		return err
+	}
+
+	if stateExistsVar {
+		return self.loadState()
+	}
+	return self.stampStartTime(RotationReasonNone)
+}
+
+// stampStartTime records that a new log file was started because of
+// reason, which is RotationReasonNone if no log was rotated.
+func (self *RollingFileAppender) stampStartTime(reason RotationReason) error {
+	now := time.Now()
+	state := newState(now)
+	if self.state != nil {
+		state.RotationCount = self.state.RotationCount
+		state.LastRotationTime = self.state.LastRotationTime
+		state.LastRotationReason = self.state.LastRotationReason
+	}
+	if reason != RotationReasonNone {
+		state.RotationCount++
+		state.LastRotationTime = now
+		state.LastRotationReason = reason
+	}
+	state.Inode = self.fileInode()
+	if self.chain != nil && self.file != nil {
+		// the head recorded must not cover lines that a crash could
+		// still lose from the write buffer or the page cache
+		if err := self.flushAndSync(); err != nil {
+			return err
+		}
+	}
+	state.ChainHead = self.chainHead()
+
+	if err := state.write(self.statePath(), self.fileOptions); err != nil {
 		return err
 	}
 	self.state = state
 	return nil
 }
+
+// fileInode returns the inode of the open log file or 0 if it is not
+// known.
+func (self *RollingFileAppender) fileInode() uint64 {
+	if self.file == nil {
+		return 0
+	}
+
+	fileInfo, err := self.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return inode(fileInfo)
+}
+
+// Status describes the current log file and its rotation history.
+type Status struct {
+	// Filename is the absolute path of the current log file
+	Filename string
+
+	// FileSize is the size of the current log file as tracked for
+	// size-based rotation
+	FileSize int64
+
+	// LogStartTime is when the current log file was started
+	LogStartTime time.Time
+
+	// RotationCount is the number of times the log has been
+	// rotated or reopened
+	RotationCount int
+
+	// LastRotationTime and LastRotationReason describe the most
+	// recent rotation.  They are zero if the log was never rotated.
+	LastRotationTime   time.Time
+	LastRotationReason RotationReason
+
+	// Inode is the inode of the current log file, or 0 on
+	// platforms without inodes
+	Inode uint64
+
+	// DiskSpaceState is what the disk space guard is currently
+	// doing about low disk space.  It is DiskSpaceOK if there is no
+	// guard.
+	DiskSpaceState DiskSpaceState
+
+	// StateVersion is the schema version of the state file the
+	// status was read from.  It is 0 for state files written by
+	// versions of slogger that did not record one.
+	StateVersion int
+}
+
+// Status returns the current log file and when and why the log was
+// last rotated, as recorded in the state file.  It is meant for
+// operators and health checks.
+func (self *RollingFileAppender) Status() Status {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	status := Status{
+		Filename:       self.absPath,
+		FileSize:       self.curFileSize,
+		DiskSpaceState: self.diskSpaceState,
+	}
+	if self.state != nil {
+		status.LogStartTime = self.state.LogStartTime
+		status.RotationCount = self.state.RotationCount
+		status.LastRotationTime = self.state.LastRotationTime
+		status.LastRotationReason = self.state.LastRotationReason
+		status.Inode = self.state.Inode
+		status.StateVersion = self.state.Version
+	}
+	return status
+}
@@ -18,10 +18,16 @@ import (
 	"github.com/mongodb/slogger/v2/slogger"
 	. "github.com/mongodb/slogger/v2/slogger/test_util"
 
+	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -87,6 +93,7 @@ func TestOldLogRemoval(test *testing.T) {
 	_, errs = logger.Logf(slogger.WARN, "This is more than 10 characters and should cause a log rotation")
 	AssertNoErrors(test, errs)
 	AssertNoErrors(test, logger.Flush())
+	appender.waitForMaintenance()
 	assertNumLogFiles(test, 3)
 }
 
@@ -219,56 +226,1587 @@ func TestReopen(test *testing.T) {
 	assertLogDoesNotContain(test, rotatedLogPath, "This is a log message 3")
 }
 
+func TestReopenCheckAppends(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithReopenCheckAppends(1))
+	defer appender.Close()
+
+	_, errs := logger.Logf(slogger.WARN, "This is a log message 1")
+	AssertNoErrors(test, errs)
+
+	rotatedLogPath := rfaTestLogPath + ".rotated"
+	if err := os.Rename(rfaTestLogPath, rotatedLogPath); err != nil {
+		test.Fatalf("os.Rename() returned an error: %v", err)
+	}
+
+	_, errs = logger.Logf(slogger.WARN, "This is a log message 2")
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger.Flush())
+
+	assertLogContains(test, rotatedLogPath, "This is a log message 1")
+	assertLogDoesNotContain(test, rotatedLogPath, "This is a log message 2")
+	assertCurrentLogContains(test, "This is a header")
+	assertCurrentLogContains(test, "Reopened")
+	assertCurrentLogContains(test, "This is a log message 2")
+}
+
+func TestReopenCheckInterval(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithReopenCheckInterval(10*time.Millisecond))
+	defer appender.Close()
+
+	if err := os.Remove(rfaTestLogPath); err != nil {
+		test.Fatalf("os.Remove() returned an error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(rfaTestLogPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			test.Fatal("Log file was not reopened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, errs := logger.Logf(slogger.WARN, "This is a log message")
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger.Flush())
+	assertCurrentLogContains(test, "This is a log message")
+}
+
+func TestFileLocking(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	// two appenders on the same path stand in for two processes
+	appender1, logger1 := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithFileLocking())
+	defer appender1.Close()
+	appender2, logger2 := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithFileLocking())
+	defer appender2.Close()
+
+	if err := appender1.Rotate(); err != nil {
+		test.Fatalf("appender1.Rotate() returned an error: %v", err)
+	}
+	assertNumLogFiles(test, 2)
+
+	// appender2 should notice that the log was already rotated
+	if err := appender2.Rotate(); err != nil {
+		test.Fatalf("appender2.Rotate() returned an error: %v", err)
+	}
+	assertNumLogFiles(test, 2)
+
+	if !appender1.state.LogStartTime.Equal(appender2.state.LogStartTime) {
+		test.Errorf(
+			"Expected appenders to share the log start time: %v != %v",
+			appender1.state.LogStartTime,
+			appender2.state.LogStartTime,
+		)
+	}
+
+	_, errs := logger1.Logf(slogger.WARN, "This is a log message 1")
+	AssertNoErrors(test, errs)
+	_, errs = logger2.Logf(slogger.WARN, "This is a log message 2")
+	AssertNoErrors(test, errs)
+
+	assertCurrentLogContains(test, "This is a log message 1")
+	assertCurrentLogContains(test, "This is a log message 2")
+}
+
+func TestFileLockingReopenCheck(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	appender1, _ := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithFileLocking().WithLogCompression(0))
+	defer appender1.Close()
+	// file locking checks before every append by default
+	appender2, logger2 := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithFileLocking())
+	defer appender2.Close()
+
+	if err := appender1.Rotate(); err != nil {
+		test.Fatalf("appender1.Rotate() returned an error: %v", err)
+	}
+	appender1.waitForMaintenance()
+	assertNumLogFiles(test, 2)
+	if _, err := os.Stat(appender1.maintenanceLockPath()); err != nil {
+		test.Errorf("Expected maintenance to take its lock: %v", err)
+	}
+
+	// appender2 should switch to the new log without stamping a
+	// new start time over appender1's
+	_, errs := logger2.Logf(slogger.WARN, "This is a log message 2")
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger2.Flush())
+
+	assertCurrentLogContains(test, "This is a log message 2")
+	if !appender1.state.LogStartTime.Equal(appender2.state.LogStartTime) {
+		test.Errorf(
+			"Expected appenders to share the log start time: %v != %v",
+			appender1.state.LogStartTime,
+			appender2.state.LogStartTime,
+		)
+	}
+	if appender2.Status().RotationCount != 1 {
+		test.Errorf("Expected appender2 to read the rotation count, got %+v", appender2.Status())
+	}
+}
+
+func TestCorruptStateRecovery(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	modTime := time.Date(2014, 6, 24, 15, 30, 12, 0, time.Local)
+	if err := ioutil.WriteFile(rfaTestLogPath, []byte("old log\n"), 0666); err != nil {
+		test.Fatal(err)
+	}
+	if err := os.Chtimes(rfaTestLogPath, modTime, modTime); err != nil {
+		test.Fatal(err)
+	}
+
+	statePath := filepath.Join(rfaTestLogDir, ".slogger-state-"+rfaTestLogFilename)
+	if err := ioutil.WriteFile(statePath, []byte(`{"logStartTi`), 0666); err != nil {
+		test.Fatal(err)
+	}
+
+	var handledErrs []error
+	builder := newTestBuilder(-1, 0, 10, false).WithErrHandler(func(err error) {
+		handledErrs = append(handledErrs, err)
+	})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	if len(handledErrs) != 1 || !IsDecodeError(handledErrs[0]) {
+		test.Errorf("Expected a single DecodeError to be handled, got %v", handledErrs)
+	}
+	if !appender.state.LogStartTime.Equal(modTime) {
+		test.Errorf("Expected log start time %v, got %v", modTime, appender.state.LogStartTime)
+	}
+
+	// the state file should have been replaced with a valid one
+	recovered, err := readState(statePath)
+	if err != nil {
+		test.Fatalf("readState() returned an error: %v", err)
+	}
+	if !recovered.LogStartTime.Equal(modTime) {
+		test.Errorf("Expected recovered log start time %v, got %v", modTime, recovered.LogStartTime)
+	}
+
+	AssertNoErrors(test, logger.Flush())
+	assertCurrentLogContains(test, "Replaced corrupt state file")
+
+	leftovers, err := filepath.Glob(statePath + ".tmp-*")
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(leftovers) != 0 {
+		test.Errorf("Expected no temporary state files, found %v", leftovers)
+	}
+}
+
+func TestStatus(test *testing.T) {
+	defer teardown()
+
+	func() {
+		appender, logger := setup(test, 100, 0, 10, false)
+		defer appender.Close()
+
+		status := appender.Status()
+		if status.RotationCount != 0 || status.LastRotationReason != RotationReasonNone {
+			test.Errorf("Unexpected status for a new log: %+v", status)
+		}
+		if status.StateVersion != stateVersion {
+			test.Errorf("Expected state version %d, got %d", stateVersion, status.StateVersion)
+		}
+		assertStatusInode(test, status)
+
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+		status = appender.Status()
+		if status.RotationCount != 1 || status.LastRotationReason != RotationReasonManual {
+			test.Errorf("Unexpected status after manual rotation: %+v", status)
+		}
+		if status.LastRotationTime.IsZero() || !status.LastRotationTime.Equal(status.LogStartTime) {
+			test.Errorf("Unexpected rotation time after manual rotation: %+v", status)
+		}
+		assertStatusInode(test, status)
+
+		_, errs := logger.Logf(slogger.WARN, strings.Repeat("This should cause a log rotation. ", 10))
+		AssertNoErrors(test, errs)
+		status = appender.Status()
+		if status.RotationCount != 2 || status.LastRotationReason != RotationReasonSize {
+			test.Errorf("Unexpected status after size-based rotation: %+v", status)
+		}
+	}()
+
+	// the status is persisted in the state file
+	appender, _ := newAppenderAndLogger(test, 100, 0, 10, false)
+	defer appender.Close()
+
+	status := appender.Status()
+	if status.RotationCount != 2 || status.LastRotationReason != RotationReasonSize {
+		test.Errorf("Unexpected status after reloading state: %+v", status)
+	}
+}
+
+func TestStatusAcrossStartupRotation(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	for i := 1; i <= 3; i++ {
+		appender, _ := newAppenderAndLogger(test, -1, 0, 10, true)
+		if i == 1 {
+			// nothing to rotate at startup the first time
+			if err := appender.Rotate(); err != nil {
+				test.Fatalf("appender.Rotate() returned an error: %v", err)
+			}
+		}
+
+		status := appender.Status()
+		if status.RotationCount != i {
+			test.Errorf("Expected rotation count %d after %d starts, got %+v", i, i, status)
+		}
+		if i > 1 && status.LastRotationReason != RotationReasonStartup {
+			test.Errorf("Expected a startup rotation, got %+v", status)
+		}
+		if err := appender.Close(); err != nil {
+			test.Fatalf("appender.Close() returned an error: %v", err)
+		}
+	}
+}
+
+func TestStatusFromNewerState(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	statePath := filepath.Join(rfaTestLogDir, ".slogger-state-"+rfaTestLogFilename)
+	newerState := fmt.Sprintf(`{"version":%d,"logStartTime":"2014-06-24T15:30:12Z","rotationCount":7}`, stateVersion+1)
+	if err := ioutil.WriteFile(statePath, []byte(newerState), 0666); err != nil {
+		test.Fatal(err)
+	}
+
+	var handledErrs []error
+	builder := newTestBuilder(-1, 0, 10, false).WithErrHandler(func(err error) {
+		handledErrs = append(handledErrs, err)
+	})
+	appender, _ := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	if len(handledErrs) != 1 || !IsDecodeError(handledErrs[0]) {
+		test.Errorf("Expected a single DecodeError to be handled, got %v", handledErrs)
+	}
+	status := appender.Status()
+	if status.StateVersion != stateVersion || status.RotationCount != 0 {
+		test.Errorf("Expected the newer state to be replaced, got %+v", status)
+	}
+}
+
+func TestStatusFromOldState(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	statePath := filepath.Join(rfaTestLogDir, ".slogger-state-"+rfaTestLogFilename)
+	oldState := `{"logStartTime":"2014-06-24T15:30:12Z"}`
+	if err := ioutil.WriteFile(statePath, []byte(oldState), 0666); err != nil {
+		test.Fatal(err)
+	}
+
+	appender, _ := newAppenderAndLogger(test, -1, 0, 10, false)
+	defer appender.Close()
+
+	status := appender.Status()
+	if status.StateVersion != 0 || status.RotationCount != 0 {
+		test.Errorf("Unexpected status from an old state file: %+v", status)
+	}
+	if status.LogStartTime.Year() != 2014 {
+		test.Errorf("Expected log start time from the old state file, got %v", status.LogStartTime)
+	}
+}
+
+func TestRotateHooks(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	var preRotated []RotationReason
+	var events []RotationEvent
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithLogCompression(1).
+		WithPreRotateHook(func(path string, reason RotationReason) {
+			if path != appenderAbsPath(test) {
+				test.Errorf("Unexpected path passed to pre-rotate hook: %s", path)
+			}
+			preRotated = append(preRotated, reason)
+		}).
+		WithPostRotateHook(func(event RotationEvent) {
+			events = append(events, event)
+		})
+	appender, _ := newAppenderAndLoggerFromBuilder(test, builder)
+
+	for i := 0; i < 2; i++ {
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+		appender.waitForMaintenance()
+	}
+
+	// Close() waits for the hooks to run
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	if len(preRotated) != 2 || preRotated[0] != RotationReasonManual {
+		test.Errorf("Unexpected pre-rotate hook calls: %v", preRotated)
+	}
+
+	// two renamed logs, the first of which was then compressed
+	if len(events) != 3 {
+		test.Fatalf("Expected 3 post-rotate hook calls, got %v", events)
+	}
+	for i, event := range events {
+		if event.Reason != RotationReasonManual {
+			test.Errorf("Unexpected reason in event %d: %+v", i, event)
+		}
+		if event.Compressed != (i == 2) {
+			test.Errorf("Unexpected compression in event %d: %+v", i, event)
+		}
+		if _, err := extractRotationTimeFromFilename(event.Path); err != nil {
+			test.Errorf("Unexpected path in event %d: %v", i, err)
+		}
+	}
+	if events[2].Path != events[0].Path+".gz" {
+		test.Errorf("Expected %s to be compressed, got %s", events[0].Path, events[2].Path)
+	}
+	if _, err := os.Stat(events[2].Path); err != nil {
+		test.Errorf("Compressed log should exist: %v", err)
+	}
+}
+
+func TestRotateHooksWithoutCompression(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	var events []RotationEvent
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithLogCompression(-1).
+		WithPostRotateHook(func(event RotationEvent) {
+			events = append(events, event)
+		})
+	appender, _ := newAppenderAndLoggerFromBuilder(test, builder)
+
+	for i := 0; i < 3; i++ {
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+		appender.waitForMaintenance()
+	}
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	if len(events) != 3 {
+		test.Errorf("Expected 3 post-rotate hook calls, got %v", events)
+	}
+	// logs that are never compressed are not remembered
+	if len(appender.rotationReasons) != 0 {
+		test.Errorf("Expected no rotation reasons to be kept, got %v", appender.rotationReasons)
+	}
+}
+
+func TestFooterAndPreviousFileHeader(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	var footerStats []FileStats
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithPreviousFileHeaderGenerator(func(previousFilename string) []string {
+			if previousFilename == "" {
+				return []string{"No previous log"}
+			}
+			return []string{"Previous log: " + filepath.Base(previousFilename)}
+		}).
+		WithFooterGenerator(func(stats FileStats) []string {
+			footerStats = append(footerStats, stats)
+			return []string{fmt.Sprintf("Footer: %d lines, reason %q", stats.Lines, stats.Reason)}
+		})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+
+	_, errs := logger.Logf(slogger.WARN, "This is a log message 1")
+	AssertNoErrors(test, errs)
+	_, errs = logger.Logf(slogger.WARN, "This is a\nmulti-line log message")
+	AssertNoErrors(test, errs)
+
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	if len(footerStats) != 2 {
+		test.Fatalf("Expected the footer to be generated twice, got %v", footerStats)
+	}
+
+	// one header line and three lines of messages
+	stats := footerStats[0]
+	if stats.Lines != 4 || stats.Reason != RotationReasonManual || stats.Path != appenderAbsPath(test) {
+		test.Errorf("Unexpected stats for the rotated log: %+v", stats)
+	}
+	if stats.FirstTimestamp.IsZero() || stats.LastTimestamp.Before(stats.FirstTimestamp) {
+		test.Errorf("Unexpected timestamps for the rotated log: %+v", stats)
+	}
+	if stats.Bytes <= 0 {
+		test.Errorf("Expected bytes to be counted: %+v", stats)
+	}
+
+	stats = footerStats[1]
+	if stats.Lines != 1 || stats.Reason != RotationReasonNone {
+		test.Errorf("Unexpected stats for the closed log: %+v", stats)
+	}
+
+	rotatedPaths, err := filepath.Glob(rfaTestLogPath + ".*")
+	if err != nil || len(rotatedPaths) != 1 {
+		test.Fatalf("Expected one rotated log, got %v (%v)", rotatedPaths, err)
+	}
+	assertLogContains(test, rotatedPaths[0], "No previous log")
+	assertLogContains(test, rotatedPaths[0], `Footer: 4 lines, reason "manual"`)
+	assertCurrentLogContains(test, "Previous log: "+filepath.Base(rotatedPaths[0]))
+	assertCurrentLogContains(test, `Footer: 1 lines, reason ""`)
+}
+
+func TestCurrentSymlink(test *testing.T) {
+	if runtime.GOOS == "windows" {
+		test.Skip("creating symlinks requires extra privileges on Windows")
+	}
+	defer teardown()
+	createLogDir(test)
+
+	linkPath := rfaTestLogPath + ".current"
+	var handledErrs []error
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithCurrentSymlink("").
+		WithErrHandler(func(err error) {
+			handledErrs = append(handledErrs, err)
+		})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	assertSymlink := func(expected string) {
+		target, err := os.Readlink(linkPath)
+		if err != nil {
+			test.Fatalf("os.Readlink() returned an error: %v", err)
+		}
+		if target != rfaTestLogFilename {
+			test.Errorf("Expected link to %s, got %s", rfaTestLogFilename, target)
+		}
+		assertLogContains(test, linkPath, expected)
+	}
+
+	_, errs := logger.Logf(slogger.WARN, "This is a log message 1")
+	AssertNoErrors(test, errs)
+	assertSymlink("This is a log message 1")
+
+	// the link should survive being removed and a rotation
+	if err := os.Remove(linkPath); err != nil {
+		test.Fatal(err)
+	}
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	_, errs = logger.Logf(slogger.WARN, "This is a log message 2")
+	AssertNoErrors(test, errs)
+	assertSymlink("This is a log message 2")
+	assertLogDoesNotContain(test, linkPath, "This is a log message 1")
+
+	// another log in the same directory gets its own link
+	otherPath := filepath.Join(rfaTestLogDir, "other.log")
+	other, err := NewBuilder(otherPath, -1, 0, 10, false, nil).WithCurrentSymlink("").Build()
+	if err != nil {
+		test.Fatalf("Build() returned an error: %v", err)
+	}
+	defer other.Close()
+	if target, err := os.Readlink(otherPath + ".current"); err != nil || target != "other.log" {
+		test.Errorf("Expected a link to other.log, got %q (%v)", target, err)
+	}
+	assertSymlink("This is a log message 2")
+
+	AssertNoErrors(test, handledErrs)
+}
+
+func TestFileModeAndDirectoryCreation(test *testing.T) {
+	if runtime.GOOS == "windows" {
+		test.Skip("Unix permissions and ownership are not supported on Windows")
+	}
+	defer teardown()
+	os.RemoveAll(rfaTestLogDir)
+
+	logPath := filepath.Join(rfaTestLogDir, "a", "b", rfaTestLogFilename)
+	appender, err := NewBuilder(logPath, -1, 0, 10, false, nil).
+		WithLogCompression(0).
+		WithFileMode(0604).
+		WithOwner(os.Getuid(), os.Getgid()).
+		WithDirectoryCreation(0705).
+		Build()
+	if err != nil {
+		test.Fatalf("Build() returned an error: %v", err)
+	}
+	if err = appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	if err = appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	assertMode := func(path string, expected os.FileMode) {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if fileInfo.Mode().Perm() != expected {
+			test.Errorf("Expected %s to have mode %v, got %v", path, expected, fileInfo.Mode().Perm())
+		}
+	}
+
+	assertMode(filepath.Join(rfaTestLogDir, "a"), 0705)
+	assertMode(filepath.Join(rfaTestLogDir, "a", "b"), 0705)
+	assertMode(logPath, 0604)
+	assertMode(filepath.Join(rfaTestLogDir, "a", "b", ".slogger-state-"+rfaTestLogFilename), 0604)
+
+	compressedPaths, err := filepath.Glob(logPath + ".*.gz")
+	if err != nil || len(compressedPaths) != 1 {
+		test.Fatalf("Expected one compressed log, got %v (%v)", compressedPaths, err)
+	}
+	assertMode(compressedPaths[0], 0604)
+}
+
+func TestDiskSpaceGuard(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	var freeLock sync.Mutex
+	var free uint64 = 1000
+	setFree := func(bytes uint64) {
+		freeLock.Lock()
+		defer freeLock.Unlock()
+		free = bytes
+	}
+	defer func(original func(string) (uint64, error)) {
+		freeDiskSpace = original
+	}(freeDiskSpace)
+	freeDiskSpace = func(string) (uint64, error) {
+		freeLock.Lock()
+		defer freeLock.Unlock()
+		return free, nil
+	}
+
+	fallbackBuffer := new(bytes.Buffer)
+	var transitions []DiskSpaceState
+	builder := newTestBuilder(-1, 0, 10, false).WithDiskSpaceGuard(DiskSpaceGuard{
+		CleanupBelow:   300,
+		MinRotatedLogs: 1,
+		ShedBelow:      200,
+		ShedLevel:      slogger.WARN,
+		FallbackBelow:  100,
+		Fallback:       slogger.NewStringAppender(fallbackBuffer),
+		OnTransition: func(from, to DiskSpaceState, freeBytes uint64) {
+			transitions = append(transitions, to)
+		},
+	})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+	}
+	assertNumLogFiles(test, 4)
+
+	// early cleanup
+	setFree(250)
+	_, errs := logger.Logf(slogger.INFO, "This is a log message 1")
+	AssertNoErrors(test, errs)
+	appender.waitForMaintenance()
+	assertNumLogFiles(test, 2)
+	assertCurrentLogContains(test, "This is a log message 1")
+
+	// shedding
+	setFree(150)
+	_, errs = logger.Logf(slogger.INFO, "This is a log message 2")
+	AssertNoErrors(test, errs)
+	_, errs = logger.Logf(slogger.WARN, "This is a log message 3")
+	AssertNoErrors(test, errs)
+	assertCurrentLogDoesNotContain(test, "This is a log message 2")
+	assertCurrentLogContains(test, "This is a log message 3")
+
+	// fallback
+	setFree(50)
+	_, errs = logger.Logf(slogger.INFO, "This is a log message 4")
+	AssertNoErrors(test, errs)
+	_, errs = logger.Logf(slogger.ERROR, "This is a log message 5")
+	AssertNoErrors(test, errs)
+	if appender.Status().DiskSpaceState != DiskSpaceFallback {
+		test.Errorf("Expected fallback state, got %v", appender.Status().DiskSpaceState)
+	}
+	assertCurrentLogDoesNotContain(test, "This is a log message 5")
+	if !strings.Contains(fallbackBuffer.String(), "This is a log message 5") ||
+		strings.Contains(fallbackBuffer.String(), "This is a log message 4") {
+		test.Errorf("Unexpected fallback contents:\n%s", fallbackBuffer.String())
+	}
+
+	// recovery
+	setFree(1000)
+	_, errs = logger.Logf(slogger.INFO, "This is a log message 6")
+	AssertNoErrors(test, errs)
+	assertCurrentLogContains(test, "This is a log message 6")
+
+	expected := []DiskSpaceState{DiskSpaceCleanup, DiskSpaceShedding, DiskSpaceFallback, DiskSpaceOK}
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		test.Errorf("Expected transitions %v, got %v", expected, transitions)
+	}
+}
+
+func TestWriteBuffer(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	builder := newTestBuilder(1000, 0, 10, false).
+		WithWriteBuffer(4096, 0).
+		WithDurability(DurabilitySyncOnWarn)
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	_, errs := logger.Logf(slogger.INFO, "This is a log message 1")
+	AssertNoErrors(test, errs)
+	assertCurrentLogDoesNotContain(test, "This is a log message 1")
+
+	_, errs = logger.Logf(slogger.WARN, "This is a log message 2")
+	AssertNoErrors(test, errs)
+	assertCurrentLogContains(test, "This is a log message 1")
+	assertCurrentLogContains(test, "This is a log message 2")
+
+	_, errs = logger.Logf(slogger.INFO, "This is a log message 3")
+	AssertNoErrors(test, errs)
+	assertCurrentLogDoesNotContain(test, "This is a log message 3")
+	AssertNoErrors(test, logger.Flush())
+	assertCurrentLogContains(test, "This is a log message 3")
+
+	// buffered logs count towards the size and end up in the
+	// rotated log
+	_, errs = logger.Logf(slogger.INFO, strings.Repeat("This should cause a log rotation. ", 30))
+	AssertNoErrors(test, errs)
+	assertNumLogFiles(test, 2)
+	rotatedPaths, err := filepath.Glob(rfaTestLogPath + ".*")
+	if err != nil || len(rotatedPaths) != 1 {
+		test.Fatalf("Expected one rotated log, got %v (%v)", rotatedPaths, err)
+	}
+	assertLogContains(test, rotatedPaths[0], "This should cause a log rotation.")
+}
+
+func TestFlushOnInterval(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	_, err := newTestBuilder(-1, 0, 10, false).WithDurability(DurabilityFlushOnInterval).Build()
+	if err == nil {
+		test.Fatal("Expected Build() to require a flush interval")
+	}
+
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithWriteBuffer(4096, 10*time.Millisecond).
+		WithDurability(DurabilityFlushOnInterval)
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	_, errs := logger.Logf(slogger.INFO, "This is a log message")
+	AssertNoErrors(test, errs)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(readLog(test, rfaTestLogPath), "This is a log message") {
+		if time.Now().After(deadline) {
+			test.Fatal("Buffered log was not flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestCompressionOnRotation(test *testing.T) {
 	defer teardown()
 
-	appender, logger := setup(test, 10, 0, 10, false)
-	appender.compressRotatedLogs = true
-	appender.maxUncompressedLogs = 1
-	defer appender.Close()
+	createLogDir(test)
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newTestBuilder(10, 0, 10, false).WithLogCompression(1))
+	defer appender.Close()
+
+	compressibleMessage := strings.Repeat("This string is easily compressible", 1000)
+
+	_, errs := logger.Logf(slogger.WARN, compressibleMessage)
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger.Flush())
+
+	checkFiles := func() (compressedLogFiles, sizeCompressedFile int) {
+		err := filepath.Walk(rfaTestLogDir, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(info.Name(), ".gz") {
+				compressedLogFiles++
+				sizeCompressedFile = int(info.Size())
+			}
+			return err
+		})
+		if err != nil {
+			test.Error(err)
+		}
+		return
+	}
+
+	appender.waitForMaintenance()
+	compressedLogFiles, _ := checkFiles()
+	assertNumLogFiles(test, 2)
+	if compressedLogFiles != 0 {
+		test.Errorf("expected to find no compressed log files")
+	}
+
+	_, errs = logger.Logf(slogger.WARN, compressibleMessage)
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger.Flush())
+	appender.waitForMaintenance()
+	compressedLogFiles, sizeCompressedFile := checkFiles()
+	assertNumLogFiles(test, 3)
+	if compressedLogFiles != 1 {
+		test.Errorf("expected to find one compressed log files")
+	}
+	if sizeCompressedFile >= len(compressibleMessage)/10 {
+		test.Errorf("expected the compressed log file size %v to be smaller than the logged bytes %v", sizeCompressedFile, len(compressibleMessage))
+	}
+}
+
+func TestOpenSet(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithLogCompression(1))
+	defer appender.Close()
+
+	for i := 0; i < 4; i++ {
+		_, errs := logger.Logf(slogger.WARN, "Set line %d", i)
+		AssertNoErrors(test, errs)
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+	}
+	_, errs := logger.Logf(slogger.WARN, "Set line 4")
+	AssertNoErrors(test, errs)
+	AssertNoErrors(test, logger.Flush())
+	appender.waitForMaintenance()
+
+	reader, err := OpenSet(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("OpenSet() returned an error: %v", err)
+	}
+	defer reader.Close()
+
+	if len(reader.Filenames()) != 5 {
+		test.Fatalf("Expected 5 logs in the set, got %v", reader.Filenames())
+	}
+	if !strings.HasSuffix(reader.Filenames()[0], ".gz") {
+		test.Errorf("Expected the oldest log to be compressed, got %s", reader.Filenames()[0])
+	}
+	if reader.Filenames()[4] != appenderAbsPath(test) {
+		test.Errorf("Expected the active log last, got %s", reader.Filenames()[4])
+	}
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		test.Fatalf("Reading the set returned an error: %v", err)
+	}
+
+	last := -1
+	for i := 0; i < 5; i++ {
+		index := bytes.Index(contents, []byte(fmt.Sprintf("Set line %d", i)))
+		if index <= last {
+			test.Fatalf("Expected \"Set line %d\" after offset %d in %q", i, last, contents)
+		}
+		last = index
+	}
+}
+
+func TestOpenSetAddsMissingNewlines(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	absPath := appenderAbsPath(test)
+	now := time.Now()
+	files := map[string]string{
+		rotatedFilename(absPath, now, 0): "first",
+		rotatedFilename(absPath, now, 1): "",
+		rotatedFilename(absPath, now, 2): "second\n",
+		absPath:                          "third",
+	}
+	for filename, contents := range files {
+		if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	reader, err := OpenSet(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("OpenSet() returned an error: %v", err)
+	}
+	defer reader.Close()
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		test.Fatalf("Reading the set returned an error: %v", err)
+	}
+	if string(contents) != "first\nsecond\nthird\n" {
+		test.Errorf("Unexpected set contents: %q", contents)
+	}
+}
+
+func TestFollow(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false))
+	defer appender.Close()
+
+	follower, err := Follow(rfaTestLogPath, FollowOptions{PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		test.Fatalf("Follow() returned an error: %v", err)
+	}
+	defer follower.Close()
+
+	waitForLine := func(substr string) {
+		lines := make(chan string)
+		go func() {
+			for {
+				line, err := follower.ReadLine()
+				if err != nil {
+					close(lines)
+					return
+				}
+				if strings.Contains(line, "Follow line") {
+					lines <- line
+					if strings.Contains(line, substr) {
+						close(lines)
+						return
+					}
+				}
+			}
+		}()
+
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					test.Fatalf("Follower stopped before %q", substr)
+				}
+				if !strings.Contains(line, substr) {
+					test.Fatalf("Expected %q, got %q", substr, line)
+				}
+				return
+			case <-timeout:
+				test.Fatalf("Timed out waiting for %q", substr)
+			}
+		}
+	}
+
+	logLine := func(i int) {
+		_, errs := logger.Logf(slogger.WARN, "Follow line %d", i)
+		AssertNoErrors(test, errs)
+	}
+
+	logLine(0)
+	waitForLine("Follow line 0")
+
+	// lines written right before rotating must not be lost
+	logLine(1)
+	if err = appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	logLine(2)
+	waitForLine("Follow line 1")
+	waitForLine("Follow line 2")
+
+	if err = os.Truncate(rfaTestLogPath, 0); err != nil {
+		test.Fatal(err)
+	}
+	logLine(3)
+	waitForLine("Follow line 3")
+
+	if err = follower.Close(); err != nil {
+		test.Errorf("follower.Close() returned an error: %v", err)
+	}
+	if _, err = follower.ReadLine(); err != io.EOF {
+		test.Errorf("Expected io.EOF after Close, got %v", err)
+	}
+}
+
+func TestPlanRetention(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	absPath := appenderAbsPath(test)
+	now := time.Now()
+	filenames := []string{
+		rotatedFilename(absPath, now.Add(-72*time.Hour), 0),
+		rotatedFilename(absPath, now.Add(-2*time.Hour), 0),
+		rotatedFilename(absPath, now.Add(-time.Hour), 0),
+		// not part of the set
+		rotatedFilename(absPath+".old", now.Add(-72*time.Hour), 0),
+	}
+	for _, filename := range filenames {
+		if err := ioutil.WriteFile(filename, []byte("rotated\n"), 0644); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	plan, err := PlanRetention(rfaTestLogPath, RetentionPolicy{
+		MaxAge:     24 * time.Hour,
+		Compressor: GzipCompressor(-1),
+	}, now)
+	if err != nil {
+		test.Fatalf("PlanRetention() returned an error: %v", err)
+	}
+	if len(plan.Remove) != 1 || plan.Remove[0] != filenames[0] {
+		test.Errorf("Expected to remove %s, got %v", filenames[0], plan.Remove)
+	}
+	if len(plan.Compress) != 2 || plan.Compress[0] != filenames[1] || plan.Compress[1] != filenames[2] {
+		test.Errorf("Expected to compress %v, got %v", filenames[1:3], plan.Compress)
+	}
+
+	AssertNoErrors(test, plan.Apply())
+	assertNumLogFiles(test, 3)
+
+	logFiles, err := LogFilesInDirectory(rfaTestLogDir)
+	if err != nil || len(logFiles) != 2 {
+		test.Errorf("Expected two log files with rotated logs, got %v (%v)", logFiles, err)
+	}
+}
+
+func TestRotationTimeSliceSkipsLongerNames(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	for _, name := range []string{
+		"foo.2015-03-01T00-00-00",
+		"foo.2015-03-02T00-00-00.gz",
+		"foo.log.2015-03-03T00-00-00",
+		"foo.log.2015-03-04T00-00-00.gz",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(rfaTestLogDir, name), []byte("x\n"), 0666); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	absPath, err := filepath.Abs(filepath.Join(rfaTestLogDir, "foo"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	rotationTimes, err := rotationTimeSliceForPath(absPath)
+	if err != nil {
+		test.Fatalf("rotationTimeSliceForPath() returned an error: %v", err)
+	}
+
+	var names []string
+	for _, rotationTime := range rotationTimes {
+		names = append(names, filepath.Base(rotationTime.Filename))
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "foo.2015-03-01T00-00-00 foo.2015-03-02T00-00-00.gz" {
+		test.Errorf("Expected only foo's rotated logs, got %v", names)
+	}
+}
+
+func TestHashChain(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	newBuilder := func() *rollingFileAppenderBuilder {
+		return newTestBuilder(-1, 0, 10, false).WithHashChain().WithLogCompression(1)
+	}
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newBuilder())
+	for i := 0; i < 3; i++ {
+		_, errs := logger.Logf(slogger.WARN, "Chained line %d", i)
+		AssertNoErrors(test, errs)
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+	}
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	// the chain carries on when appending after a restart
+	appender, logger = newAppenderAndLoggerFromBuilder(test, newBuilder())
+	_, errs := logger.Logf(slogger.WARN, "Chained line 3")
+	AssertNoErrors(test, errs)
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	verification, err := VerifyChain(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("VerifyChain() returned an error: %v", err)
+	}
+	if len(verification.Files) != 4 || !strings.HasSuffix(verification.Files[0], ".gz") {
+		test.Errorf("Expected 4 logs, the oldest compressed, got %v", verification.Files)
+	}
+	assertLogContains(test, appenderAbsPath(test), "Hash chain head ")
+
+	assertTampered := func(what string) {
+		if _, err := VerifyChain(rfaTestLogPath); !IsChainError(err) {
+			test.Errorf("Expected a ChainError after %s, got %v", what, err)
+		}
+	}
+
+	rewrite := func(path string, change func(string) string) (restore func()) {
+		original, err := ioutil.ReadFile(path)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(change(string(original))), 0644); err != nil {
+			test.Fatal(err)
+		}
+		return func() {
+			if err := ioutil.WriteFile(path, original, 0644); err != nil {
+				test.Fatal(err)
+			}
+		}
+	}
+
+	restore := rewrite(appenderAbsPath(test), func(contents string) string {
+		return strings.Replace(contents, "Chained line 3", "Chained line 4", 1)
+	})
+	assertTampered("editing a line")
+	restore()
+
+	restore = rewrite(appenderAbsPath(test), func(contents string) string {
+		lines := strings.SplitAfter(contents, "\n")
+		return strings.Join(lines[:len(lines)-2], "")
+	})
+	assertTampered("removing the last line")
+	restore()
+
+	// a crash during a write leaves a line without a newline
+	restore = rewrite(appenderAbsPath(test), func(contents string) string {
+		return contents + "[2026/10/18 12:00:00.000] [test.warn] [rolli"
+	})
+	if verification, err = VerifyChain(rfaTestLogPath); err != nil {
+		test.Errorf("VerifyChain() returned an error after a torn write: %v", err)
+	} else if verification.TornLines != 1 {
+		test.Errorf("Expected 1 torn line, got %d", verification.TornLines)
+	}
+	restore()
 
-	compressibleMessage := strings.Repeat("This string is easily compressible", 1000)
+	middle := verification.Files[1]
+	if err = os.Rename(middle, middle+".moved"); err != nil {
+		test.Fatal(err)
+	}
+	assertTampered("removing a rotated log")
+	if err = os.Rename(middle+".moved", middle); err != nil {
+		test.Fatal(err)
+	}
 
-	_, errs := logger.Logf(slogger.WARN, compressibleMessage)
+	if _, err = VerifyChain(rfaTestLogPath); err != nil {
+		test.Errorf("VerifyChain() returned an error after restoring the logs: %v", err)
+	}
+}
+
+func TestHashChainRestartAfterTornWrite(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	newBuilder := func() *rollingFileAppenderBuilder {
+		return newTestBuilder(-1, 0, 10, false).WithHashChain()
+	}
+
+	appender, logger := newAppenderAndLoggerFromBuilder(test, newBuilder())
+	_, errs := logger.Logf(slogger.WARN, "one")
 	AssertNoErrors(test, errs)
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	for _, message := range []string{"two", "three"} {
+		_, errs = logger.Logf(slogger.WARN, message)
+		AssertNoErrors(test, errs)
+	}
 	AssertNoErrors(test, logger.Flush())
 
-	checkFiles := func() (compressedLogFiles, sizeCompressedFile int) {
-		err := filepath.Walk(rfaTestLogDir, func(_ string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if strings.HasSuffix(info.Name(), ".gz") {
-				compressedLogFiles++
-				sizeCompressedFile = int(info.Size())
+	// crash in the middle of a write, without closing
+	appender.stopMaintenance()
+	file, err := os.OpenFile(appenderAbsPath(test), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if _, err = file.WriteString("[2026/10/18 12:00:00.000] [test.warn] [rolli"); err != nil {
+		test.Fatal(err)
+	}
+	file.Close()
+
+	appender, logger = newAppenderAndLoggerFromBuilder(test, newBuilder())
+	_, errs = logger.Logf(slogger.WARN, "four")
+	AssertNoErrors(test, errs)
+	if err = appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	verification, err := VerifyChain(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("VerifyChain() returned an error after restarting: %v", err)
+	}
+	if verification.TornLines != 1 {
+		test.Errorf("Expected 1 torn line, got %d", verification.TornLines)
+	}
+	assertCurrentLogContains(test, "four")
+}
+
+func TestHashChainWithWriteBuffer(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	builder := newTestBuilder(-1, 0, 10, false).WithHashChain().WithWriteBuffer(4096, 0)
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	_, errs := logger.Logf(slogger.WARN, "Buffered line")
+	AssertNoErrors(test, errs)
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+
+	// the head in the state file must already be on disk, as a crash
+	// would lose what is still buffered
+	state, err := readState(appender.statePath())
+	if err != nil {
+		test.Fatalf("readState() returned an error: %v", err)
+	}
+	head, ok := lastChainTag(appenderAbsPath(test))
+	if !ok || head.String() != state.ChainHead {
+		test.Errorf("Expected the log to end with the chain head %s in the state file, got %s (%v)", state.ChainHead, head, ok)
+	}
+}
+
+func testEncryptionKeys(keys map[string][]byte, currentID string) EncryptionKeys {
+	return EncryptionKeys{
+		Current: func() (string, []byte, error) {
+			return currentID, keys[currentID], nil
+		},
+		Lookup: func(keyID string) ([]byte, error) {
+			key, ok := keys[keyID]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q", keyID)
 			}
-			return err
-		})
-		if err != nil {
-			test.Error(err)
+			return key, nil
+		},
+	}
+}
+
+func TestEncryption(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	keys := map[string][]byte{"key1": bytes.Repeat([]byte{1}, 32)}
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithLogCompression(0).
+		WithEncryption(testEncryptionKeys(keys, "key1"))
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	for i := 0; i < 2; i++ {
+		_, errs := logger.Logf(slogger.WARN, "Customer %d", i)
+		AssertNoErrors(test, errs)
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
 		}
-		return
 	}
+	appender.waitForMaintenance()
 
-	compressedLogFiles, _ := checkFiles()
-	assertNumLogFiles(test, 2)
-	if compressedLogFiles != 0 {
-		test.Errorf("expected to find no compressed log files")
+	encrypted, err := filepath.Glob(appenderAbsPath(test) + ".*.gz.enc")
+	if err != nil || len(encrypted) != 2 {
+		test.Fatalf("Expected two encrypted logs, got %v (%v)", encrypted, err)
+	}
+	raw, err := ioutil.ReadFile(encrypted[0])
+	if err != nil {
+		test.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("Customer")) {
+		test.Error("Encrypted log contains plain text")
+	}
+	if !bytes.HasPrefix(raw, []byte(encryptionMagic+"\x04key1")) {
+		test.Errorf("Unexpected encrypted log header: %q", raw[:13])
 	}
 
-	_, errs = logger.Logf(slogger.WARN, compressibleMessage)
+	reader, err := OpenSet(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("OpenSet() returned an error: %v", err)
+	}
+	contents, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		test.Fatalf("Reading the set returned an error: %v", err)
+	}
+	if !bytes.Contains(contents, []byte("Customer 0")) || !bytes.Contains(contents, []byte("Customer 1")) {
+		test.Errorf("Expected decrypted logs in %q", contents)
+	}
+
+	// a flipped bit anywhere after the header is detected
+	raw[len(raw)/2] ^= 1
+	if err = ioutil.WriteFile(encrypted[0], raw, 0644); err != nil {
+		test.Fatal(err)
+	}
+	if reader, err = OpenSet(rfaTestLogPath); err == nil {
+		_, err = ioutil.ReadAll(reader)
+		reader.Close()
+	}
+	if err == nil {
+		test.Error("Expected an error reading a tampered encrypted log")
+	}
+}
+
+func TestEncryptionWithWriteOnlyKeys(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	keys := map[string][]byte{"key2": bytes.Repeat([]byte{2}, 32)}
+	reader := EncryptingCompressor(GzipCompressor(0), testEncryptionKeys(keys, "key2"))
+	compressorsLock.RLock()
+	previous, registered := compressors[reader.Suffix()]
+	compressorsLock.RUnlock()
+	defer func() {
+		compressorsLock.Lock()
+		defer compressorsLock.Unlock()
+		if registered {
+			compressors[reader.Suffix()] = previous
+		} else {
+			delete(compressors, reader.Suffix())
+		}
+		setRotatedTimeRegExp(compressedSuffixesLocked())
+	}()
+	RegisterCompressor(reader)
+
+	writeOnly := testEncryptionKeys(keys, "key2")
+	writeOnly.Lookup = nil
+	builder := newTestBuilder(-1, 0, 10, false).WithLogCompression(0).WithEncryption(writeOnly)
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	_, errs := logger.Logf(slogger.WARN, "Customer 2")
 	AssertNoErrors(test, errs)
-	AssertNoErrors(test, logger.Flush())
-	compressedLogFiles, sizeCompressedFile := checkFiles()
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	appender.waitForMaintenance()
+
+	// Build() must not have replaced the registered reader
+	setReader, err := OpenSet(rfaTestLogPath)
+	if err != nil {
+		test.Fatalf("OpenSet() returned an error: %v", err)
+	}
+	contents, err := ioutil.ReadAll(setReader)
+	setReader.Close()
+	if err != nil {
+		test.Fatalf("Reading the set returned an error: %v", err)
+	}
+	if !bytes.Contains(contents, []byte("Customer 2")) {
+		test.Errorf("Expected decrypted logs in %q", contents)
+	}
+}
+
+func TestEncryptionRequiresCompression(test *testing.T) {
+	keys := map[string][]byte{"key1": bytes.Repeat([]byte{1}, 32)}
+	_, err := newTestBuilder(-1, 0, 10, false).WithEncryption(testEncryptionKeys(keys, "key1")).Build()
+	if err == nil {
+		test.Error("Expected Build() to fail without compression")
+	}
+}
+
+func TestEncryptingCompressor(test *testing.T) {
+	keys := map[string][]byte{
+		"old": bytes.Repeat([]byte{1}, 16),
+		"new": bytes.Repeat([]byte{2}, 32),
+	}
+	compressor := EncryptingCompressor(GzipCompressor(0), testEncryptionKeys(keys, "new"))
+	if compressor.Suffix() != ".gz.enc" {
+		test.Errorf("Unexpected suffix %s", compressor.Suffix())
+	}
+
+	// spans several chunks
+	var plain bytes.Buffer
+	for i := 0; plain.Len() < 3*encryptionChunkSize; i++ {
+		fmt.Fprintf(&plain, "line %d\n", i)
+	}
+
+	var encrypted bytes.Buffer
+	writer, err := compressor.NewWriter(&encrypted)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if _, err = writer.Write(plain.Bytes()); err != nil {
+		test.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		test.Fatal(err)
+	}
+
+	decrypt := func(data []byte, keys EncryptionKeys) ([]byte, error) {
+		reader, err := EncryptingCompressor(GzipCompressor(0), keys).NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	}
+
+	// the key ID in the file is used, not the current key
+	decrypted, err := decrypt(encrypted.Bytes(), testEncryptionKeys(keys, "old"))
+	if err != nil || !bytes.Equal(decrypted, plain.Bytes()) {
+		test.Errorf("Round trip failed: %v", err)
+	}
+
+	if _, err = decrypt(encrypted.Bytes()[:encrypted.Len()-100], testEncryptionKeys(keys, "new")); err == nil {
+		test.Error("Expected an error decrypting a truncated log")
+	}
+
+	appended := append(append([]byte(nil), encrypted.Bytes()...), "appended"...)
+	if _, err = decrypt(appended, testEncryptionKeys(keys, "new")); err != errEncryptedTrailing {
+		test.Errorf("Expected errEncryptedTrailing decrypting a log with data appended, got %v", err)
+	}
+
+	if _, err = decrypt(encrypted.Bytes(), testEncryptionKeys(map[string][]byte{"new": keys["old"]}, "new")); err == nil {
+		test.Error("Expected an error decrypting with the wrong key")
+	}
+}
+
+func TestCompressor(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	builder := newTestBuilder(-1, 0, 2, false).
+		WithLogCompression(0).
+		WithCompressor(ZlibCompressor(9))
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	for i := 0; i < 3; i++ {
+		_, errs := logger.Logf(slogger.WARN, "Compress me %d", i)
+		AssertNoErrors(test, errs)
+		if err := appender.Rotate(); err != nil {
+			test.Fatalf("appender.Rotate() returned an error: %v", err)
+		}
+	}
+	appender.waitForMaintenance()
+
+	// compressed logs count towards maxRotatedLogs
 	assertNumLogFiles(test, 3)
-	if compressedLogFiles != 1 {
-		test.Errorf("expected to find one compressed log files")
+
+	compressedPaths, err := filepath.Glob(rfaTestLogPath + ".*.zz")
+	if err != nil {
+		test.Fatal(err)
 	}
-	if sizeCompressedFile >= len(compressibleMessage)/10 {
-		test.Errorf("expected the compressed log file size %v to be smaller than the logged bytes %v", sizeCompressedFile, len(compressibleMessage))
+	if len(compressedPaths) != 2 {
+		test.Fatalf("Expected 2 compressed logs, found %v", compressedPaths)
+	}
+
+	f, err := os.Open(compressedPaths[1])
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer f.Close()
+	r, err := compressorForFilename(f.Name()).NewReader(f)
+	if err != nil {
+		test.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if !strings.Contains(string(contents), "Compress me 2") {
+		test.Errorf("Unexpected decompressed contents: %s", contents)
+	}
+}
+
+type testCompressor struct {
+	Compressor
+}
+
+func (testCompressor) Suffix() string {
+	return ".test-z"
+}
+
+func TestRegisterCompressor(test *testing.T) {
+	filename := "foo.log.2014-06-24T15-30-12-3.test-z"
+	if _, err := extractRotationTimeFromFilename(filename); err == nil {
+		test.Fatalf("%s should not be recognized before registration", filename)
+	}
+
+	RegisterCompressor(testCompressor{GzipCompressor(1)})
+
+	rotationTime, err := extractRotationTimeFromFilename(filename)
+	if err != nil {
+		test.Fatalf("extractRotationTimeFromFilename() returned an error: %v", err)
+	}
+	if rotationTime.Serial != 3 {
+		test.Errorf("Expected serial 3, got %d", rotationTime.Serial)
+	}
+	if !isCompressed(filename) {
+		test.Errorf("%s should be considered compressed", filename)
+	}
+}
+
+func TestInvalidCompressionLevel(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	_, err := newTestBuilder(-1, 0, 2, false).
+		WithLogCompression(0).
+		WithCompressor(GzipCompressor(42)).
+		Build()
+	if err == nil {
+		test.Fatal("Expected Build() to fail with an invalid compression level")
+	}
+}
+
+func TestCompressionAtStartup(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	// leave behind rotated logs as if a previous run had crashed
+	// before compressing them
+	now := time.Now()
+	for serial := 0; serial < 3; serial++ {
+		path := rotatedFilename(rfaTestLogPath, now, serial)
+		if err := ioutil.WriteFile(path, []byte("left over\n"), 0666); err != nil {
+			test.Fatalf("Failed to create rotated log: %v", err)
+		}
+	}
+
+	appender, _ := newAppenderAndLoggerFromBuilder(test, newTestBuilder(-1, 0, 10, false).WithLogCompression(1))
+
+	// Close() should wait for the startup compression to finish
+	if err := appender.Close(); err != nil {
+		test.Fatalf("appender.Close() returned an error: %v", err)
+	}
+
+	assertNumLogFiles(test, 4)
+	for serial := 0; serial < 3; serial++ {
+		path := rotatedFilename(rfaTestLogPath, now, serial)
+		_, err := os.Stat(path + ".gz")
+		if (serial < 2) != (err == nil) {
+			test.Errorf("Unexpected compression state for %s: %v", path, err)
+		}
 	}
 }
 
+func TestMaintenanceErrHandler(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	errCh := make(chan error, 1)
+	builder := newTestBuilder(10, 0, 10, false).
+		WithLogCompression(0).
+		WithErrHandler(func(err error) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	// a directory in the way of the compressed file makes
+	// compression fail
+	now := time.Now()
+	if err := os.Mkdir(rotatedFilename(rfaTestLogPath, now, 0)+".gz", 0777); err != nil {
+		test.Fatalf("os.Mkdir() returned an error: %v", err)
+	}
+	if err := ioutil.WriteFile(rotatedFilename(rfaTestLogPath, now, 0), []byte("x\n"), 0666); err != nil {
+		test.Fatalf("Failed to create rotated log: %v", err)
+	}
+
+	_, errs := logger.Logf(slogger.WARN, "This is more than 10 characters and should cause a log rotation")
+	AssertNoErrors(test, errs)
+	appender.waitForMaintenance()
+
+	select {
+	case err := <-errCh:
+		if _, ok := err.(*MinorRotationError); !ok {
+			test.Errorf("Expected a MinorRotationError, got %#v", err)
+		}
+	default:
+		test.Error("Expected errHandler to be called")
+	}
+}
+
+func TestMaintenanceLogsToAppender(test *testing.T) {
+	defer teardown()
+	createLogDir(test)
+
+	var logger *slogger.Logger
+	var inHookOnce sync.Once
+	inHook := make(chan struct{})
+	proceed := make(chan struct{})
+	builder := newTestBuilder(-1, 0, 10, false).
+		WithPostRotateHook(func(event RotationEvent) {
+			inHookOnce.Do(func() { close(inHook) })
+			<-proceed
+			logger.Logf(slogger.INFO, "Rotated %s", filepath.Base(event.Path))
+		})
+	appender, logger := newAppenderAndLoggerFromBuilder(test, builder)
+	defer appender.Close()
+
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	<-inHook
+
+	// with the worker busy in the hook, queue another pass and wait
+	// for it while the hook goes on to log to the appender
+	if err := appender.Rotate(); err != nil {
+		test.Fatalf("appender.Rotate() returned an error: %v", err)
+	}
+	waited := make(chan struct{})
+	go func() {
+		appender.waitForMaintenance()
+		close(waited)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(proceed)
+
+	select {
+	case <-waited:
+	case <-time.After(10 * time.Second):
+		test.Fatal("waitForMaintenance() deadlocked with a hook logging to the appender")
+	}
+	assertCurrentLogContains(test, "Rotated ")
+}
+
+func assertStatusInode(test *testing.T, status Status) {
+	fileInfo, err := os.Stat(rfaTestLogPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	if status.Inode != inode(fileInfo) {
+		test.Errorf("Expected inode %d, got %d", inode(fileInfo), status.Inode)
+	}
+}
+
+func appenderAbsPath(test *testing.T) string {
+	absPath, err := filepath.Abs(rfaTestLogPath)
+	if err != nil {
+		test.Fatal(err)
+	}
+	return absPath
+}
+
 func assertCurrentLogContains(test *testing.T, expected string) {
 	assertLogContains(test, rfaTestLogPath, expected)
 }
@@ -318,17 +1856,11 @@ func createLogDir(test *testing.T) {
 }
 
 func newAppenderAndLogger(test *testing.T, maxFileSize int64, maxDuration time.Duration, maxRotatedLogs int, rotateIfExists bool) (appender *RollingFileAppender, logger *slogger.Logger) {
-	appender, err := New(
-		rfaTestLogPath,
-		maxFileSize,
-		maxDuration,
-		maxRotatedLogs,
-		rotateIfExists,
-		func() []string {
-			return []string{"This is a header", "more header"}
-		},
-	)
+	return newAppenderAndLoggerFromBuilder(test, newTestBuilder(maxFileSize, maxDuration, maxRotatedLogs, rotateIfExists))
+}
 
+func newAppenderAndLoggerFromBuilder(test *testing.T, builder *rollingFileAppenderBuilder) (appender *RollingFileAppender, logger *slogger.Logger) {
+	appender, err := builder.Build()
 	if err != nil {
 		test.Fatal("NewRollingFileAppender() failed: " + err.Error())
 	}
@@ -341,6 +1873,19 @@ func newAppenderAndLogger(test *testing.T, maxFileSize int64, maxDuration time.D
 	return
 }
 
+func newTestBuilder(maxFileSize int64, maxDuration time.Duration, maxRotatedLogs int, rotateIfExists bool) *rollingFileAppenderBuilder {
+	return NewBuilder(
+		rfaTestLogPath,
+		maxFileSize,
+		maxDuration,
+		maxRotatedLogs,
+		rotateIfExists,
+		func() []string {
+			return []string{"This is a header", "more header"}
+		},
+	)
+}
+
 func numLogFiles() (int, error) {
 	cwd, err := os.Open(rfaTestLogDir)
 	if err != nil {
@@ -2,8 +2,11 @@ package rolling_file_appender
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,10 +34,34 @@ func (self RotationTimeSlice) Swap(i, j int) {
 	self[i], self[j] = self[j], self[i]
 }
 
-var rotatedTimeRegExp = regexp.MustCompile(`\.(\d+-\d\d-\d\dT\d\d-\d\d-\d\d)(-(\d+))?(\.gz)?$`)
+var rotatedTimeRegExpLock sync.RWMutex
+
+// rotatedTimeRegExp matches the suffix of rotated logs.  It is
+// rebuilt by setRotatedTimeRegExp whenever a Compressor is
+// registered.
+var rotatedTimeRegExp *regexp.Regexp
+
+func setRotatedTimeRegExp(compressedSuffixes []string) {
+	quoted := make([]string, len(compressedSuffixes))
+	for i, suffix := range compressedSuffixes {
+		quoted[i] = regexp.QuoteMeta(suffix)
+	}
+
+	rotatedTimeRegExpLock.Lock()
+	defer rotatedTimeRegExpLock.Unlock()
+	rotatedTimeRegExp = regexp.MustCompile(
+		`\.(\d+-\d\d-\d\dT\d\d-\d\d-\d\d)(-(\d+))?(` + strings.Join(quoted, "|") + `)?$`,
+	)
+}
+
+func getRotatedTimeRegExp() *regexp.Regexp {
+	rotatedTimeRegExpLock.RLock()
+	defer rotatedTimeRegExpLock.RUnlock()
+	return rotatedTimeRegExp
+}
 
 func extractRotationTimeFromFilename(filename string) (*RotationTime, error) {
-	match := rotatedTimeRegExp.FindStringSubmatch(filename)
+	match := getRotatedTimeRegExp().FindStringSubmatch(filename)
 
 	if match == nil {
 		return nil, fmt.Errorf("Filename does not match rotation time format: %s", filename)
@@ -65,3 +92,40 @@ func extractRotationTimeFromFilename(filename string) (*RotationTime, error) {
 
 	return &RotationTime{rotatedTime, serial, filename}, nil
 }
+
+// rotationTimeSliceForPath returns the rotated logs, compressed or
+// not, of the log at absPath in no particular order.
+func rotationTimeSliceForPath(absPath string) (RotationTimeSlice, error) {
+	candidateFilenames, err := filepath.Glob(absPath + ".*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	rotationTimes := make(RotationTimeSlice, 0, len(candidateFilenames))
+
+	for _, candidateFilename := range candidateFilenames {
+		// skip the rotated logs of longer names, e.g. foo.log's
+		// when looking for foo's
+		if base, ok := rotatedLogBase(candidateFilename); !ok || base != absPath {
+			continue
+		}
+
+		rotationTime, err := extractRotationTimeFromFilename(candidateFilename)
+		if err == nil {
+			rotationTimes = append(rotationTimes, rotationTime)
+		}
+	}
+
+	return rotationTimes, nil
+}
+
+// rotatedLogBase returns the name of the log file that filename is a
+// rotated log of.
+func rotatedLogBase(filename string) (string, bool) {
+	match := getRotatedTimeRegExp().FindStringIndex(filename)
+	if match == nil || match[0] == 0 {
+		return "", false
+	}
+	return filename[:match[0]], true
+}
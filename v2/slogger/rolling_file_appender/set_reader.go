@@ -0,0 +1,202 @@
+package rolling_file_appender
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SetReader reads a log set: the rotated logs of a log file in
+// chronological order followed by the log file itself.  Compressed
+// rotated logs are transparently decompressed.  If a log does not end
+// with a newline, one is added so that lines from different logs are
+// never joined.
+type SetReader struct {
+	filenames []string
+	next      int
+
+	cur            io.ReadCloser
+	lastByte       byte
+	curEmpty       bool
+	missingNewline bool
+}
+
+// OpenSet returns a SetReader for the log set of the log file at path.
+// The set is determined when OpenSet is called; logs rotated
+// afterwards are not read.  The returned SetReader must be closed.
+func OpenSet(path string) (*SetReader, error) {
+	filenames, err := SetFilenames(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetReader{filenames: filenames}, nil
+}
+
+// SetFilenames returns the filenames of the log set of the log file at
+// path in chronological order.  The log file itself is last, if it
+// exists.
+func SetFilenames(path string) ([]string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rotationTimes, err := rotationTimeSliceForPath(absPath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(rotationTimes)
+
+	filenames := make([]string, 0, len(rotationTimes)+1)
+	for _, rotationTime := range rotationTimes {
+		filenames = append(filenames, rotationTime.Filename)
+	}
+
+	if _, err = os.Stat(absPath); err == nil {
+		filenames = append(filenames, absPath)
+	} else if !os.IsNotExist(err) {
+		return nil, &StatError{absPath, err}
+	}
+
+	return filenames, nil
+}
+
+// Filenames returns the logs the SetReader reads, in order.
+func (self *SetReader) Filenames() []string {
+	return self.filenames
+}
+
+func (self *SetReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		if self.missingNewline {
+			p[0] = '\n'
+			self.missingNewline = false
+			return 1, nil
+		}
+
+		if self.cur == nil {
+			if self.next >= len(self.filenames) {
+				return 0, io.EOF
+			}
+
+			if err := self.openNext(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		n, err := self.cur.Read(p)
+		if n > 0 {
+			self.lastByte = p[n-1]
+			self.curEmpty = false
+		}
+
+		if err == io.EOF {
+			self.missingNewline = !self.curEmpty && self.lastByte != '\n'
+			if err = self.closeCurrent(); err != nil {
+				return n, err
+			}
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+
+		return n, err
+	}
+}
+
+// openNext opens the next log, skipping logs that were removed since
+// the set was listed.  A log that was compressed since is read from
+// its compressed file.
+func (self *SetReader) openNext() error {
+	filename := self.filenames[self.next]
+	self.next++
+
+	reader, err := openLog(filename)
+	if os.IsNotExist(err) {
+		for _, suffix := range compressedSuffixes() {
+			if reader, err = openLog(filename + suffix); !os.IsNotExist(err) {
+				filename += suffix
+				break
+			}
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return &OpenError{filename, err}
+	}
+
+	self.cur = reader
+	self.curEmpty = true
+	return nil
+}
+
+func (self *SetReader) closeCurrent() error {
+	if self.cur == nil {
+		return nil
+	}
+	err := self.cur.Close()
+	self.cur = nil
+	return err
+}
+
+// OpenLog opens a single log, decompressing it if its filename ends
+// with the suffix of a registered Compressor.  The returned reader
+// must be closed.
+func OpenLog(filename string) (io.ReadCloser, error) {
+	reader, err := openLog(filename)
+	if err != nil {
+		return nil, &OpenError{filename, err}
+	}
+	return reader, nil
+}
+
+func openLog(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor := compressorForFilename(filename)
+	if compressor == nil {
+		return file, nil
+	}
+
+	reader, err := compressor.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &decompressingReader{reader, file}, nil
+}
+
+// decompressingReader closes both the decompressor and the file
+// underneath it.
+type decompressingReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (self *decompressingReader) Close() error {
+	err := self.ReadCloser.Close()
+	if fileErr := self.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// Close closes the log currently being read.
+func (self *SetReader) Close() error {
+	self.next = len(self.filenames)
+	self.missingNewline = false
+	return self.closeCurrent()
+}
@@ -2,6 +2,7 @@ package rolling_file_appender
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -14,11 +15,45 @@ import (
 // types should not be changed as that will break reading in older
 // versions of the state file.
 type state struct {
+	Version      int       `json:"version"`
 	LogStartTime time.Time `json:"logStartTime"`
+
+	RotationCount      int            `json:"rotationCount"`
+	LastRotationTime   time.Time      `json:"lastRotationTime"`
+	LastRotationReason RotationReason `json:"lastRotationReason"`
+
+	// Inode is the inode of the log file the state describes
+	Inode uint64 `json:"inode"`
+
+	// ChainHead is the latest hash of the hash chain, if any, as of
+	// the last rotation or Close()
+	ChainHead string `json:"chainHead,omitempty"`
 }
 
+// stateVersion is the current schema version of the state file.  State
+// files written before versioning was introduced read in as 0.
+const stateVersion = 1
+
+// RotationReason is why a log file was rotated
+type RotationReason string
+
+const (
+	RotationReasonNone    RotationReason = ""
+	RotationReasonSize    RotationReason = "size"
+	RotationReasonTime    RotationReason = "time"
+	RotationReasonManual  RotationReason = "manual"
+	RotationReasonStartup RotationReason = "startup"
+
+	// RotationReasonReopen means the log was rotated externally
+	// and then reopened
+	RotationReasonReopen RotationReason = "reopen"
+)
+
 func newState(logStartTime time.Time) *state {
-	return &state{logStartTime}
+	return &state{
+		Version:      stateVersion,
+		LogStartTime: logStartTime,
+	}
 }
 
 func readState(path string) (*state, error) {
@@ -33,6 +68,18 @@ func readState(path string) (*state, error) {
 	if err = decoder.Decode(&decodedState); err != nil {
 		return nil, DecodeError{path, err}
 	}
+	if decodedState == nil {
+		return nil, DecodeError{path, fmt.Errorf("no state")}
+	}
+
+	// a newer version may have changed the meaning of fields, so
+	// treat its state file like a corrupt one
+	if decodedState.Version > stateVersion {
+		return nil, DecodeError{
+			path,
+			fmt.Errorf("unsupported state version %d (newest supported is %d)", decodedState.Version, stateVersion),
+		}
+	}
 
 	return decodedState, nil
 }
@@ -49,22 +96,48 @@ func stateExists(path string) (bool, error) {
 	return true, nil
 }
 
-func (self *state) write(path string) error {
-	file, err := createHidden(path)
+// write atomically replaces the state file at path.  The state is
+// written to a temporary file which is synced and then renamed over
+// path, so a crash never leaves a partially written state file
+// behind.
+func (self *state) write(path string, options fileOptions) error {
+	tmpPath := fmt.Sprintf("%s.tmp-%d", path, os.Getpid())
+	file, err := createHidden(tmpPath, options.perm())
 	if err != nil {
-		return OpenError{path, err}
+		return OpenError{tmpPath, err}
 	}
+	defer os.Remove(tmpPath) // fails harmlessly once renamed
 	defer file.Close()
 
+	if err = options.apply(file); err != nil {
+		return OpenError{tmpPath, err}
+	}
+
 	encoder := json.NewEncoder(file)
 	if err = encoder.Encode(self); err != nil {
-		return EncodeError{path, err}
+		return EncodeError{tmpPath, err}
+	}
+
+	if err = file.Sync(); err != nil {
+		return SyncError{tmpPath, err}
+	}
+
+	if err = file.Close(); err != nil {
+		return CloseError{tmpPath, err}
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return RenameError{tmpPath, path, err}
 	}
 
 	return nil
 }
 
 func (self *RollingFileAppender) statePath() string {
-	newBase := ".slogger-state-" + filepath.Base(self.absPath)
-	return filepath.Join(filepath.Dir(self.absPath), newBase)
+	return statePathFor(self.absPath)
+}
+
+func statePathFor(absPath string) string {
+	newBase := ".slogger-state-" + filepath.Base(absPath)
+	return filepath.Join(filepath.Dir(absPath), newBase)
 }
@@ -0,0 +1,16 @@
+package rolling_file_appender
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// statfsFreeSpace is like the one in statfs_unix.go, but OpenBSD names
+// the fields of syscall.Statfs_t differently.
+func statfsFreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(path), &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.F_bavail) * uint64(stat.F_bsize), nil
+}
@@ -0,0 +1,12 @@
+//go:build !(darwin || dragonfly || freebsd || linux || openbsd)
+// +build !darwin,!dragonfly,!freebsd,!linux,!openbsd
+
+package rolling_file_appender
+
+import (
+	"errors"
+)
+
+func statfsFreeSpace(path string) (uint64, error) {
+	return 0, errors.New("checking free disk space is not supported on this platform")
+}
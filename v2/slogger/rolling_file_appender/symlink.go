@@ -0,0 +1,36 @@
+package rolling_file_appender
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// updateCurrentSymlink points the current symlink, if configured, at
+// the log file, putting it back if it was removed or replaced.  A new
+// link is created next to the old one and renamed over it so that
+// readers never find the link missing.  The lock should be held when
+// calling this.
+func (self *RollingFileAppender) updateCurrentSymlink() {
+	if self.currentSymlink == "" {
+		return
+	}
+
+	// prefer a relative link so the directory can be moved
+	target := self.absPath
+	if rel, err := filepath.Rel(filepath.Dir(self.currentSymlink), self.absPath); err == nil {
+		target = rel
+	}
+
+	tmpPath := fmt.Sprintf("%s.tmp-%d", self.currentSymlink, os.Getpid())
+	os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		self.handleError(SymlinkError{self.currentSymlink, target, err})
+		return
+	}
+
+	if err := os.Rename(tmpPath, self.currentSymlink); err != nil {
+		os.Remove(tmpPath)
+		self.handleError(SymlinkError{self.currentSymlink, target, err})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signal_handler reopens or rotates appenders when the
+// process receives a signal.  For example:
+//
+//	handler := signal_handler.Install(
+//		map[os.Signal]signal_handler.Action{
+//			syscall.SIGHUP:  signal_handler.Reopen(appender),
+//			syscall.SIGUSR1: signal_handler.Rotate(appender),
+//		},
+//		errHandler,
+//	)
+//	defer handler.Stop()
+
+package signal_handler
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// An Action is run whenever one of the signals it is installed for is
+// received.  It returns the errors that occurred.
+type Action func() []error
+
+// Reopen returns an Action that calls Reopen() on each of reopeners.
+func Reopen(reopeners ...slogger.Reopener) Action {
+	return func() []error {
+		var errs []error
+		for _, reopener := range reopeners {
+			if err := reopener.Reopen(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+}
+
+// Rotate returns an Action that calls Rotate() on each of rotators.
+func Rotate(rotators ...slogger.Rotator) Action {
+	return func() []error {
+		var errs []error
+		for _, rotator := range rotators {
+			if err := rotator.Rotate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+}
+
+// A SignalHandler runs Actions for the signals it was installed for
+// until it is stopped.
+type SignalHandler struct {
+	actions    map[os.Signal]Action
+	errHandler func(error)
+	signalCh   chan os.Signal
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	doneCh     chan struct{}
+}
+
+// Install starts handling the signals in actions by running the
+// corresponding Action.  Errors returned by the Actions are passed to
+// errHandler, which can be nil.  Call Stop() to restore the default
+// behavior of the signals.  Signals with a nil Action are not handled,
+// and an error is passed to errHandler for each of them.  With no
+// actions, no signals are handled.
+func Install(actions map[os.Signal]Action, errHandler func(error)) *SignalHandler {
+	handler := &SignalHandler{
+		actions:    make(map[os.Signal]Action, len(actions)),
+		errHandler: errHandler,
+		signalCh:   make(chan os.Signal, len(actions)),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+
+	signals := make([]os.Signal, 0, len(actions))
+	for sig, action := range actions {
+		if action == nil {
+			handler.handleError(fmt.Errorf("signal_handler: No Action for signal %v", sig))
+			continue
+		}
+		handler.actions[sig] = action
+		signals = append(signals, sig)
+	}
+
+	// signal.Notify() without signals would relay all of them,
+	// keeping SIGINT and SIGTERM from ending the process
+	if len(signals) == 0 {
+		close(handler.doneCh)
+		return handler
+	}
+
+	signal.Notify(handler.signalCh, signals...)
+	go handler.listenForSignals()
+
+	return handler
+}
+
+// Stop stops handling signals and waits for any Action in progress
+// to finish.  It is safe to call Stop more than once.
+func (self *SignalHandler) Stop() {
+	signal.Stop(self.signalCh)
+
+	self.stopOnce.Do(func() {
+		close(self.stopCh)
+	})
+	<-self.doneCh
+}
+
+func (self *SignalHandler) listenForSignals() {
+	defer close(self.doneCh)
+
+	for {
+		select {
+		case <-self.stopCh:
+			return
+		case sig := <-self.signalCh:
+			action, ok := self.actions[sig]
+			if !ok {
+				continue
+			}
+
+			for _, err := range action() {
+				self.handleError(err)
+			}
+		}
+	}
+}
+
+func (self *SignalHandler) handleError(err error) {
+	if self.errHandler != nil {
+		self.errHandler(err)
+	}
+}
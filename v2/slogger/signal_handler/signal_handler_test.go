@@ -0,0 +1,150 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos linux netbsd openbsd solaris
+
+package signal_handler
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type countingAppender struct {
+	reopens chan struct{}
+	rotates chan struct{}
+}
+
+func newCountingAppender() *countingAppender {
+	return &countingAppender{
+		reopens: make(chan struct{}, 10),
+		rotates: make(chan struct{}, 10),
+	}
+}
+
+func (self *countingAppender) Reopen() error {
+	self.reopens <- struct{}{}
+	return nil
+}
+
+func (self *countingAppender) Rotate() error {
+	self.rotates <- struct{}{}
+	return errors.New("rotate failed")
+}
+
+func TestSignals(test *testing.T) {
+	appender1 := newCountingAppender()
+	appender2 := newCountingAppender()
+	errCh := make(chan error, 10)
+
+	handler := Install(
+		map[os.Signal]Action{
+			syscall.SIGUSR1: Reopen(appender1, appender2),
+			syscall.SIGUSR2: Rotate(appender1),
+		},
+		func(err error) {
+			errCh <- err
+		},
+	)
+	defer handler.Stop()
+
+	sendSignal(test, syscall.SIGUSR1)
+	waitFor(test, appender1.reopens, "appender1 reopen")
+	waitFor(test, appender2.reopens, "appender2 reopen")
+
+	sendSignal(test, syscall.SIGUSR2)
+	waitFor(test, appender1.rotates, "appender1 rotate")
+
+	select {
+	case err := <-errCh:
+		if err.Error() != "rotate failed" {
+			test.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		test.Error("Expected errHandler to be called")
+	}
+
+	if len(appender2.rotates) != 0 {
+		test.Error("appender2 should not have been rotated")
+	}
+}
+
+func TestStop(test *testing.T) {
+	appender := newCountingAppender()
+	handler := Install(map[os.Signal]Action{syscall.SIGUSR1: Reopen(appender)}, nil)
+	handler.Stop()
+	handler.Stop()
+
+	// keep the process from dying of SIGUSR1 now that the handler
+	// no longer catches it
+	other := Install(map[os.Signal]Action{syscall.SIGUSR1: func() []error { return nil }}, nil)
+	defer other.Stop()
+
+	sendSignal(test, syscall.SIGUSR1)
+	time.Sleep(50 * time.Millisecond)
+
+	if len(appender.reopens) != 0 {
+		test.Error("Stopped handler should not reopen")
+	}
+}
+
+func TestInstallWithoutActions(test *testing.T) {
+	handler := Install(nil, nil)
+
+	// no signals are relayed, so there is nothing to listen for
+	select {
+	case <-handler.doneCh:
+	default:
+		test.Error("Expected a handler without actions not to listen for signals")
+	}
+	handler.Stop()
+}
+
+func TestInstallWithNilAction(test *testing.T) {
+	var errs []error
+	handler := Install(map[os.Signal]Action{syscall.SIGUSR1: nil}, func(err error) {
+		errs = append(errs, err)
+	})
+	defer handler.Stop()
+
+	if len(errs) != 1 {
+		test.Errorf("Expected one error for the nil Action, got %v", errs)
+	}
+	if len(handler.actions) != 0 {
+		test.Error("Expected the signal with a nil Action not to be handled")
+	}
+	select {
+	case <-handler.doneCh:
+	default:
+		test.Error("Expected a handler without actions not to listen for signals")
+	}
+}
+
+func sendSignal(test *testing.T, sig syscall.Signal) {
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		test.Fatalf("syscall.Kill() returned an error: %v", err)
+	}
+}
+
+func waitFor(test *testing.T, ch chan struct{}, what string) {
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		test.Fatalf("Timed out waiting for %s", what)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// An appender that streams logs to HTTP clients as Server-Sent Events.
+// An SSEAppender is also the http.Handler clients connect to.  Each
+// client picks the logs it gets with query parameters:
+//
+//	level=warn          logs of at least this level
+//	prefix=agent        logs whose prefix starts with this
+//	context=key         logs whose context has this key
+//	context=key=value   logs whose context has this key and value
+//
+// All given conditions must hold.  context may be repeated.
+//
+// Each log is sent as a "log" event with the log as JSON.  Logs are
+// buffered per client; when a client is too slow to keep up, logs
+// for it are dropped rather than slowing down logging, and a
+// "dropped" event with the number of dropped logs is sent before the
+// next log that gets through.  For example:
+//
+//	curl -N 'http://localhost:8080/debug/stream?level=info&context=request=1234'
+
+package sse_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keepAliveInterval is how often a comment is sent to idle clients, so
+// that proxies do not close their connections.
+const keepAliveInterval = 15 * time.Second
+
+type filter struct {
+	level   slogger.Level
+	prefix  string
+	context []contextMatch
+}
+
+type contextMatch struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+func (self *filter) matches(log *slogger.Log) bool {
+	if log.Level < self.level || !strings.HasPrefix(log.Prefix, self.prefix) {
+		return false
+	}
+	for _, match := range self.context {
+		if log.Context == nil {
+			return false
+		}
+		value, found := log.Context.Get(match.key)
+		if !found || (!match.anyValue && fmt.Sprint(value) != match.value) {
+			return false
+		}
+	}
+	return true
+}
+
+type client struct {
+	// dropped is accessed atomically, so it comes first to be 64-bit
+	// aligned on 32-bit platforms.
+	dropped uint64
+
+	filter filter
+	events chan []byte
+}
+
+type SSEAppender struct {
+	bufferSize int
+
+	// The lock protects clients and closed.
+	clients map[*client]struct{}
+	closed  bool
+	lock    sync.RWMutex
+
+	done chan struct{}
+}
+
+// New returns an SSEAppender that buffers up to bufferSize logs for
+// each client.
+func New(bufferSize int) *SSEAppender {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &SSEAppender{
+		bufferSize: bufferSize,
+		clients:    make(map[*client]struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func formatEvent(log *slogger.Log) ([]byte, error) {
+	data, err := json.Marshal(slogger.NewJSONLog(log))
+	if err != nil {
+		return nil, err
+	}
+	return []byte("event: log\ndata: " + string(data) + "\n\n"), nil
+}
+
+// Append sends log to the clients whose filters it matches.  It never
+// blocks on a client.
+func (self *SSEAppender) Append(log *slogger.Log) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	// format the log at most once, and only now, so that later
+	// changes to its arguments do not race with the clients
+	var event []byte
+	for client := range self.clients {
+		if !client.filter.matches(log) {
+			continue
+		}
+		if event == nil {
+			var err error
+			if event, err = formatEvent(log); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case client.events <- event:
+		default:
+			atomic.AddUint64(&client.dropped, 1)
+		}
+	}
+	return nil
+}
+
+func (self *SSEAppender) Flush() error {
+	return nil
+}
+
+// Clients returns the number of connected clients.
+func (self *SSEAppender) Clients() int {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return len(self.clients)
+}
+
+// Close disconnects all clients and refuses new ones.
+func (self *SSEAppender) Close() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if !self.closed {
+		self.closed = true
+		close(self.done)
+	}
+}
+
+func parseFilter(r *http.Request) (filter, error) {
+	query := r.URL.Query()
+	result := filter{level: slogger.TRACE, prefix: query.Get("prefix")}
+
+	if levelStr := query.Get("level"); levelStr != "" {
+		level, err := slogger.NewLevel(levelStr)
+		if err != nil {
+			return result, err
+		}
+		result.level = level
+	}
+
+	for _, condition := range query["context"] {
+		key, value, hasValue := strings.Cut(condition, "=")
+		if key == "" {
+			return result, fmt.Errorf("invalid context condition %q", condition)
+		}
+		result.context = append(result.context, contextMatch{key, value, !hasValue})
+	}
+	return result, nil
+}
+
+func (self *SSEAppender) addClient(client *client) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.closed {
+		return false
+	}
+	self.clients[client] = struct{}{}
+	return true
+}
+
+func (self *SSEAppender) removeClient(client *client) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.clients, client)
+}
+
+// ServeHTTP streams logs to the client until it disconnects or the
+// SSEAppender is closed.
+func (self *SSEAppender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := &client{filter: filter, events: make(chan []byte, self.bufferSize)}
+	if !self.addClient(client) {
+		http.Error(w, "log stream is closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer self.removeClient(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var event []byte
+		select {
+		case <-r.Context().Done():
+			return
+		case <-self.done:
+			return
+		case <-keepAlive.C:
+			event = []byte(": keep-alive\n\n")
+		case event = <-client.events:
+		}
+
+		if dropped := atomic.SwapUint64(&client.dropped, 0); dropped > 0 {
+			if _, err := fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped); err != nil {
+				return
+			}
+		}
+		if _, err := w.Write(event); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
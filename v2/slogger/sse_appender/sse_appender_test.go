@@ -0,0 +1,219 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sse_appender
+
+import (
+	"github.com/mongodb/slogger/v2/slogger"
+
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitForClients(test *testing.T, appender *SSEAppender, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for appender.Clients() != n {
+		if time.Now().After(deadline) {
+			test.Fatalf("Expected %d clients, got %d", n, appender.Clients())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// readMessages reads the messages of n log events from an event stream.
+func readMessages(test *testing.T, reader *bufio.Reader, n int) []string {
+	var messages []string
+	for len(messages) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			test.Fatalf("Error reading stream: %v", err)
+		}
+		data := strings.TrimPrefix(strings.TrimSuffix(line, "\n"), "data: ")
+		if data == strings.TrimSuffix(line, "\n") {
+			continue
+		}
+		var log slogger.JSONLog
+		if err = json.Unmarshal([]byte(data), &log); err != nil {
+			test.Fatalf("Error decoding %q: %v", data, err)
+		}
+		messages = append(messages, log.Message)
+	}
+	return messages
+}
+
+func TestStreaming(test *testing.T) {
+	appender := New(100)
+	server := httptest.NewServer(appender)
+	defer server.Close()
+
+	logger := &slogger.Logger{Prefix: "agent", Appenders: []slogger.Appender{appender}}
+	otherLogger := &slogger.Logger{Prefix: "other", Appenders: []slogger.Appender{appender}}
+
+	all, err := http.Get(server.URL)
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer all.Body.Close()
+	if contentType := all.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		test.Errorf("Expected text/event-stream, got %s", contentType)
+	}
+
+	filtered, err := http.Get(server.URL + "?level=warn&prefix=ag&context=request=7&context=user")
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer filtered.Body.Close()
+
+	waitForClients(test, appender, 2)
+
+	requestContext := slogger.NewContext()
+	requestContext.Add("request", 7)
+	requestContext.Add("user", "alice")
+	otherContext := slogger.NewContext()
+	otherContext.Add("request", 8)
+	otherContext.Add("user", "bob")
+
+	logger.Logf(slogger.INFO, "info %d", 1)
+	logger.LogfWithContext(slogger.WARN, "warn with other request", otherContext)
+	otherLogger.LogfWithContext(slogger.ERROR, "error from other", requestContext)
+	logger.LogfWithContext(slogger.ERROR, "error with request", requestContext)
+
+	got := readMessages(test, bufio.NewReader(all.Body), 4)
+	if strings.Join(got, ",") != "info 1,warn with other request,error from other,error with request" {
+		test.Errorf("Unexpected logs for unfiltered client: %v", got)
+	}
+
+	got = readMessages(test, bufio.NewReader(filtered.Body), 1)
+	if got[0] != "error with request" {
+		test.Errorf("Unexpected log for filtered client: %v", got)
+	}
+
+	appender.Close()
+	if _, err = io.ReadAll(all.Body); err != nil {
+		test.Errorf("Expected the stream to end cleanly after Close: %v", err)
+	}
+	waitForClients(test, appender, 0)
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		test.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable {
+		test.Errorf("Expected status 503 after Close, got %d", response.StatusCode)
+	}
+}
+
+func TestBadRequests(test *testing.T) {
+	appender := New(1)
+	for _, target := range []string{"/?level=loud", "/?context==x"} {
+		recorder := httptest.NewRecorder()
+		appender.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			test.Errorf("%s: expected status 400, got %d", target, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	appender.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		test.Errorf("Expected status 405, got %d", recorder.Code)
+	}
+}
+
+// blockingRecorder blocks the first write of a log event until
+// released, like a slow client.
+type blockingRecorder struct {
+	*httptest.ResponseRecorder
+	blocked chan struct{}
+	release chan struct{}
+	logs    int32 // accessed atomically
+}
+
+func (self *blockingRecorder) Write(p []byte) (int, error) {
+	if strings.HasPrefix(string(p), "event: log") {
+		if atomic.LoadInt32(&self.logs) == 0 {
+			close(self.blocked)
+			<-self.release
+		}
+		defer atomic.AddInt32(&self.logs, 1)
+	}
+	return self.ResponseRecorder.Write(p)
+}
+
+func TestSlowClient(test *testing.T) {
+	appender := New(2)
+	logger := &slogger.Logger{Prefix: "slow", Appenders: []slogger.Appender{appender}}
+
+	recorder := &blockingRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		blocked:          make(chan struct{}),
+		release:          make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	served := make(chan struct{})
+	go func() {
+		appender.ServeHTTP(recorder, request)
+		close(served)
+	}()
+	waitForClients(test, appender, 1)
+
+	logger.Logf(slogger.INFO, "first")
+	<-recorder.blocked
+
+	// two logs fit in the buffer, the other three are dropped
+	for _, message := range []string{"second", "third", "fourth", "fifth", "sixth"} {
+		logger.Logf(slogger.INFO, message)
+	}
+	close(recorder.release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&recorder.logs) < 3 {
+		if time.Now().After(deadline) {
+			test.Fatalf("Expected 3 logs to be written, got %d", atomic.LoadInt32(&recorder.logs))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-served
+
+	body := recorder.Body.String()
+	dropped := strings.Index(body, "event: dropped\ndata: 3\n\n")
+	if dropped < 0 {
+		test.Fatalf("Expected 3 dropped logs to be reported, got:\n%s", body)
+	}
+	for _, message := range []string{`"first"`, `"second"`, `"third"`} {
+		if !strings.Contains(body, message) {
+			test.Errorf("Expected %s in stream:\n%s", message, body)
+		}
+	}
+	for _, message := range []string{`"fourth"`, `"fifth"`, `"sixth"`} {
+		if strings.Contains(body, message) {
+			test.Errorf("Expected %s to be dropped:\n%s", message, body)
+		}
+	}
+	if dropped > strings.Index(body, `"second"`) {
+		test.Errorf("Expected drops to be reported before the next log:\n%s", body)
+	}
+}
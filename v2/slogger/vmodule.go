@@ -0,0 +1,199 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// VModule sets the level of logs per call site, like glog's -vmodule
+// flag.  A spec is a comma-separated list of rules of the form
+// pattern=level, such as
+//
+//	rolling*=trace,queue=debug,func:*.(*Queue).Push=trace
+//
+// A pattern is a glob, as for path.Match, that is matched against
+//
+//   - the name of the source file of the call site without its
+//     directories or ".go" suffix, e.g. rolling*.  A pattern with
+//     slashes is matched against as many trailing directories, e.g.
+//     rolling_file_appender/*.
+//   - with a func: prefix, the name of the calling function qualified
+//     by its package name, e.g. func:queue.* or func:*.(*Queue).Push.
+//
+// The first matching rule sets the level.  Call sites that match no
+// rule use the default level.  The rule for each call site is worked
+// out once and cached by program counter, so rules cost little after
+// the first log from a call site.  As with the Filename of logs, call
+// sites are looked for past frames in the files given to
+// IgnoreThisFilenameToo; files added later are not honored for call
+// sites already cached.
+type VModule struct {
+	rules        []vmoduleRule
+	defaultLevel *AtomicLevel
+
+	// sites maps program counters to *vmoduleSite
+	sites sync.Map
+}
+
+type vmoduleRule struct {
+	pattern  string
+	function bool
+	level    Level
+}
+
+type vmoduleSite struct {
+	// ignored is true for the frames of slogger and of files given to
+	// IgnoreThisFilenameToo
+	ignored bool
+
+	// rule is nil for call sites that match no rule
+	rule *vmoduleRule
+}
+
+type VModuleError struct {
+	Rule   string
+	Reason string
+}
+
+func (self VModuleError) Error() string {
+	return fmt.Sprintf("Invalid vmodule rule %q: %s", self.Rule, self.Reason)
+}
+
+func IsVModuleError(err error) bool {
+	_, ok := err.(VModuleError)
+	return ok
+}
+
+// ParseVModule parses spec.  Call sites that match no rule get the
+// level of defaultLevel, which may be changed later, or TRACE if
+// defaultLevel is nil.
+func ParseVModule(spec string, defaultLevel *AtomicLevel) (*VModule, error) {
+	if defaultLevel == nil {
+		defaultLevel = NewAtomicLevel(TRACE)
+	}
+	vmodule := &VModule{defaultLevel: defaultLevel}
+
+	for _, ruleStr := range strings.Split(spec, ",") {
+		ruleStr = strings.TrimSpace(ruleStr)
+		if ruleStr == "" {
+			continue
+		}
+
+		pattern, levelStr, ok := strings.Cut(ruleStr, "=")
+		if !ok {
+			return nil, VModuleError{ruleStr, "expected pattern=level"}
+		}
+		level, err := NewLevel(strings.TrimSpace(levelStr))
+		if err != nil {
+			return nil, VModuleError{ruleStr, err.Error()}
+		}
+
+		rule := vmoduleRule{pattern: strings.TrimSpace(pattern), level: level}
+		if functionPattern := strings.TrimPrefix(rule.pattern, "func:"); functionPattern != rule.pattern {
+			rule.pattern = functionPattern
+			rule.function = true
+		}
+		if rule.pattern == "" {
+			return nil, VModuleError{ruleStr, "empty pattern"}
+		}
+		if _, err = path.Match(rule.pattern, ""); err != nil {
+			return nil, VModuleError{ruleStr, err.Error()}
+		}
+
+		vmodule.rules = append(vmodule.rules, rule)
+	}
+
+	return vmodule, nil
+}
+
+// Filter is a TurboFilter letting through the logs that the rules
+// enable at their call sites.
+func (self *VModule) Filter(level Level, messageFmt string, args ...interface{}) bool {
+	var pcs [16]uintptr
+	// skip runtime.Callers and Filter
+	n := runtime.Callers(2, pcs[:])
+
+	for _, pc := range pcs[:n] {
+		site := self.site(pc)
+		if site.ignored {
+			continue
+		}
+		if site.rule != nil {
+			return level >= site.rule.level
+		}
+		break
+	}
+	return self.defaultLevel.Enabled(level)
+}
+
+func (self *VModule) site(pc uintptr) *vmoduleSite {
+	if site, ok := self.sites.Load(pc); ok {
+		return site.(*vmoduleSite)
+	}
+
+	// one program counter can stand for several frames when calls
+	// were inlined, innermost first
+	site := &vmoduleSite{ignored: true}
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && !containsAnyIgnoredFilename(frame.File) {
+			site = &vmoduleSite{rule: self.match(frame.File, frame.Function)}
+			break
+		}
+		if !more {
+			break
+		}
+	}
+
+	self.sites.Store(pc, site)
+	return site
+}
+
+// match returns the first rule matching the source file or function,
+// or nil.
+func (self *VModule) match(file, function string) *vmoduleRule {
+	file = strings.TrimSuffix(file, ".go")
+	if index := strings.LastIndex(function, "/"); index >= 0 {
+		function = function[index+1:]
+	}
+
+	for i := range self.rules {
+		rule := &self.rules[i]
+		name := function
+		if !rule.function {
+			name = lastPathElements(file, strings.Count(rule.pattern, "/")+1)
+		}
+		if matched, _ := path.Match(rule.pattern, name); matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+// lastPathElements returns the last n slash-separated elements of
+// filepath.
+func lastPathElements(filepath string, n int) string {
+	index := len(filepath)
+	for ; n > 0 && index >= 0; n-- {
+		index = strings.LastIndex(filepath[:index], "/")
+	}
+	return filepath[index+1:]
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 MongoDB, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slogger
+
+import (
+	"testing"
+)
+
+//go:noinline
+func logFromHelper(logger *Logger, level Level) {
+	logger.Logf(level, "from helper")
+}
+
+func newVModuleLogger(test *testing.T, spec string, defaultLevel *AtomicLevel) (*Logger, *countingAppender) {
+	vmodule, err := ParseVModule(spec, defaultLevel)
+	if err != nil {
+		test.Fatalf("ParseVModule(%q): %v", spec, err)
+	}
+	counter := &countingAppender{}
+	return &Logger{
+		Prefix:       "vmodule",
+		Appenders:    []Appender{counter},
+		TurboFilters: []TurboFilter{vmodule.Filter},
+	}, counter
+}
+
+func TestVModule(test *testing.T) {
+	defaultLevel := NewAtomicLevel(WARN)
+
+	check := func(spec string, log func(logger *Logger), expected int) {
+		logger, counter := newVModuleLogger(test, spec, defaultLevel)
+		// log twice to go through the cache
+		log(logger)
+		log(logger)
+		if counter.count != 2*expected {
+			test.Errorf("%q: expected %d logs, got %d", spec, 2*expected, counter.count)
+		}
+	}
+	logHere := func(level Level) func(*Logger) {
+		return func(logger *Logger) { logger.Logf(level, "here") }
+	}
+	logFromHelperAt := func(level Level) func(*Logger) {
+		return func(logger *Logger) { logFromHelper(logger, level) }
+	}
+
+	check("", logHere(INFO), 0)
+	check("", logHere(WARN), 1)
+	check("vmodule_test=trace", logHere(TRACE), 1)
+	check("vmod*=debug", logHere(TRACE), 0)
+	check("vmod*=debug", logHere(DEBUG), 1)
+	check("other*=trace", logHere(INFO), 0)
+	check("slogger/vmodule_*=trace", logHere(TRACE), 1)
+	check("other/vmodule_*=trace", logHere(TRACE), 0)
+
+	// rules can also set a stricter level than the default
+	check("vmodule_test=error", logHere(WARN), 0)
+
+	// the first matching rule wins
+	check("vmodule_test=error,vmod*=trace", logHere(INFO), 0)
+	check("vmod*=trace,vmodule_test=error", logHere(INFO), 1)
+
+	check("func:slogger.logFromHelper=debug", logFromHelperAt(DEBUG), 1)
+	check("func:slogger.logFromHelper=debug", logHere(DEBUG), 0)
+	check("func:*.logFrom*=trace", logFromHelperAt(TRACE), 1)
+	check("func:slogger.TestVModule.*=trace", logHere(TRACE), 1)
+
+	// the default level can change after call sites were cached
+	logger, counter := newVModuleLogger(test, "other=trace", defaultLevel)
+	logger.Logf(INFO, "hidden")
+	defaultLevel.SetLevel(INFO)
+	logger.Logf(INFO, "shown")
+	if counter.count != 1 {
+		test.Errorf("Expected a change of the default level to apply, got %d logs", counter.count)
+	}
+}
+
+func TestVModuleTurboFilterDirectly(test *testing.T) {
+	vmodule, err := ParseVModule("vmodule_test=debug", NewAtomicLevel(ERROR))
+	if err != nil {
+		test.Fatal(err)
+	}
+	var filter TurboFilter = vmodule.Filter
+	if !filter(DEBUG, "Evaluation should continue") {
+		test.Errorf("Expected the rule for this file to apply")
+	}
+	if filter(TRACE, "Evaluation should halt") {
+		test.Errorf("Expected lesser level to halt evaluation")
+	}
+}
+
+func TestParseVModuleErrors(test *testing.T) {
+	for _, spec := range []string{"queue", "queue=loud", "=debug", "func:=debug", "[=debug"} {
+		if _, err := ParseVModule(spec, nil); !IsVModuleError(err) {
+			test.Errorf("%q: expected a VModuleError, got %v", spec, err)
+		}
+	}
+
+	vmodule, err := ParseVModule(" queue = debug , ,rolling*=trace,", nil)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(vmodule.rules) != 2 || vmodule.rules[0].pattern != "queue" || vmodule.rules[1].level != TRACE {
+		test.Errorf("Unexpected rules: %+v", vmodule.rules)
+	}
+	if !vmodule.Filter(TRACE, "") {
+		test.Errorf("Expected a nil default level to let everything through")
+	}
+}